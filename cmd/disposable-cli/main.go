@@ -0,0 +1,417 @@
+// Command disposable-cli checks email addresses and lints disposable domain
+// lists offline, using the same loading and matching code as the webhook.
+//
+// Exit codes: 0 when there is nothing to report, 1 when addresses were
+// rejected or a list has findings, 2 on usage or load errors.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/resolver"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+// Exit codes
+const (
+	exitOK       = 0
+	exitFindings = 1
+	exitError    = 2
+)
+
+const usage = `Usage: disposable-cli <command> [flags] [args]
+
+Commands:
+  check      Check email addresses (from args or stdin) against lists
+  diff       Show domains added and removed between two list versions
+  lint       Report invalid, duplicate and non-canonical list entries
+  normalize  Print a list as sorted, unique, normalized domains
+
+Run "disposable-cli <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(exitError)
+	}
+
+	var code int
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "check":
+		code = runCheck(args)
+	case "diff":
+		code = runDiff(args)
+	case "lint":
+		code = runLint(args)
+	case "normalize":
+		code = runNormalize(args)
+	case "help", "-h", "--help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", cmd, usage)
+		code = exitError
+	}
+	os.Exit(code)
+}
+
+// listFlag collects a repeatable or comma-separated flag
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// repeatedFlag collects a repeatable flag whose values may contain commas,
+// such as regular expressions
+type repeatedFlag []string
+
+func (r *repeatedFlag) String() string { return strings.Join(*r, " ") }
+
+func (r *repeatedFlag) Set(value string) error {
+	*r = append(*r, value)
+	return nil
+}
+
+// newFlagSet creates a flag set that reports errors instead of exiting
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: disposable-cli %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// runCheck checks addresses the way the webhook does and prints one
+// tab-separated line per address: email, verdict, matched domain, source
+// and origin (the syntax error for invalid addresses, the DNS reason for
+// undeliverable ones)
+func runCheck(args []string) int {
+	var lists, allowFiles, allowDomains, denyFiles, denyDomains listFlag
+	fs := newFlagSet("check", "[email...]")
+	fs.Var(&lists, "list", "list source (URL, file or directory); repeatable, defaults to $DISPOSABLE_LIST_URLS")
+	mode := fs.String("mode", service.ListModeFallback, `how multiple lists are combined: "fallback" or "union"`)
+	syntax := fs.String("syntax", service.SyntaxModeStandard, `email syntax mode: "strict", "standard" or "lenient"`)
+	fs.Var(&allowFiles, "allow-file", "allowlist file; repeatable")
+	fs.Var(&allowDomains, "allow", "allowed domain; repeatable")
+	fs.Var(&denyFiles, "deny-file", "denylist file; repeatable")
+	fs.Var(&denyDomains, "deny", "denied domain; repeatable")
+	dnsChecks := fs.Bool("dns", false, "also check MX records and domain existence")
+	var mxHosts, mxLists, nsLists listFlag
+	fs.Var(&mxHosts, "mx-host", "known disposable mail exchanger (with -dns); repeatable")
+	fs.Var(&mxLists, "mx-list", "list of disposable mail exchanger hosts, IPs and CIDR ranges (with -dns); repeatable")
+	fs.Var(&nsLists, "ns-list", "list of disposable nameserver hosts, IPs and CIDR ranges (with -dns); repeatable")
+	var freeMailLists listFlag
+	freeMail := fs.Bool("free-mail", false, "also report addresses at free mailbox providers")
+	fs.Var(&freeMailLists, "free-mail-list", "list of free mailbox provider domains (with -free-mail, defaults to the built-in providers); repeatable")
+	var localPartPatterns repeatedFlag
+	localPart := fs.Bool("local-part", false, "also report role accounts, random local parts and local part pattern matches")
+	fs.Var(&localPartPatterns, "local-part-pattern", "regular expression matched against the whole local part (with -local-part); repeatable")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+
+	if len(lists) == 0 {
+		lists.Set(os.Getenv("DISPOSABLE_LIST_URLS"))
+	}
+	if len(lists) == 0 {
+		fmt.Fprintln(os.Stderr, "no list given: use -list or set DISPOSABLE_LIST_URLS")
+		return exitError
+	}
+	if *mode != service.ListModeFallback && *mode != service.ListModeUnion {
+		fmt.Fprintf(os.Stderr, "invalid -mode %q\n", *mode)
+		return exitError
+	}
+	switch *syntax {
+	case service.SyntaxModeStrict, service.SyntaxModeStandard, service.SyntaxModeLenient:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid -syntax %q\n", *syntax)
+		return exitError
+	}
+
+	localParts, err := service.NewLocalPartChecker(service.LocalPartOptions{Patterns: localPartPatterns})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -local-part-pattern: %v\n", err)
+		return exitError
+	}
+
+	overrides := service.Overrides{
+		AllowFiles:   allowFiles,
+		AllowDomains: allowDomains,
+		DenyFiles:    denyFiles,
+		DenyDomains:  denyDomains,
+	}
+	if err := overrides.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid overrides: %v\n", err)
+		return exitError
+	}
+
+	dnsOptions := service.DNSOptions{DisposableMXHosts: mxHosts}
+	if *dnsChecks {
+		dnsOptions.MXListURLs = mxLists
+		dnsOptions.NSListURLs = nsLists
+		dnsOptions.Resolver = resolver.NewNetResolver("", 5*time.Second)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelError}))
+	svc := service.NewDisposableEmailService(service.Options{
+		ListURLs:         lists,
+		ListMode:         *mode,
+		FailurePolicy:    service.FailurePolicyClosed,
+		SyntaxMode:       *syntax,
+		DNS:              dnsOptions,
+		FreeMailListURLs: freeMailLists,
+		LocalParts:       localParts,
+		Overrides:        overrides,
+	}, logger)
+	// -free-mail and -local-part select rejection categories as the
+	// webhook's "reject" parameter does
+	var categories []string
+	if *freeMail {
+		categories = append(categories, service.RejectFreeMail)
+	}
+	if *localPart {
+		categories = append(categories, service.RejectRoleAccount, service.RejectLocalPartPattern, service.RejectRandomLocalPart)
+	}
+	reject, err := service.ParseRejections(categories)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	if _, err := svc.Refresh(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load lists: %v\n", err)
+		return exitError
+	}
+
+	emails := fs.Args()
+	if len(emails) == 0 {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			if email := strings.TrimSpace(scanner.Text()); email != "" {
+				emails = append(emails, email)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to read stdin: %v\n", err)
+			return exitError
+		}
+	}
+
+	code := exitOK
+	for _, email := range emails {
+		verdict, err := svc.CheckContext(service.WithAudit(context.Background()), email)
+		switch {
+		case errors.Is(err, domain.ErrInvalidEmail):
+			fmt.Printf("%s\tinvalid\t-\t-\t%v\n", email, err)
+			code = exitFindings
+			continue
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", email, err)
+			return exitError
+		}
+
+		decision := service.Decide(verdict, reject)
+		switch {
+		case decision.Category == service.RejectFreeMail:
+			fmt.Printf("%s\tfree_mail\t%s\t%s\t%s\n", email, verdict.FreeMail, domain.SourceFreeMail, verdict.FreeMailOrigin)
+		case decision.Category != "":
+			fmt.Printf("%s\t%s\t%s\tlocal_part\t-\n", email, decision.Category, decision.Detail)
+		case decision.Outcome == service.DecisionDisposable:
+			fmt.Printf("%s\tdisposable\t%s\t%s\t%s\n", email, verdict.MatchedDomain, verdict.Source, verdict.Origin)
+		case decision.Outcome == service.DecisionUndeliverable:
+			fmt.Printf("%s\tundeliverable\t-\tdns\t%s\n", email, verdict.Undeliverable)
+		default:
+			fmt.Printf("%s\tallowed\t%s\t%s\t%s\n", email, dash(verdict.MatchedDomain), dash(verdict.Source), dash(verdict.Origin))
+		}
+		if decision.Reject {
+			code = exitFindings
+		}
+	}
+	return code
+}
+
+// runDiff prints "+domain" for domains only in the new list and "-domain"
+// for domains only in the old one
+func runDiff(args []string) int {
+	fs := newFlagSet("diff", "<old> <new>")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return exitError
+	}
+
+	before, err := service.LoadList(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load %s: %v\n", fs.Arg(0), err)
+		return exitError
+	}
+	after, err := service.LoadList(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load %s: %v\n", fs.Arg(1), err)
+		return exitError
+	}
+
+	var added, removed []string
+	for d := range after {
+		if !before[d] {
+			added = append(added, d)
+		}
+	}
+	for d := range before {
+		if !after[d] {
+			removed = append(removed, d)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	for _, d := range removed {
+		fmt.Printf("-%s\n", d)
+	}
+	for _, d := range added {
+		fmt.Printf("+%s\n", d)
+	}
+	fmt.Fprintf(os.Stderr, "%d added, %d removed (%d -> %d domains)\n",
+		len(added), len(removed), len(before), len(after))
+
+	if len(added) > 0 || len(removed) > 0 {
+		return exitFindings
+	}
+	return exitOK
+}
+
+// runLint reports problems in list files as "file:pos: problem: entry"
+func runLint(args []string) int {
+	fs := newFlagSet("lint", "<file>...")
+	format := fs.String("format", "", "list format (txt, json, hosts, csv, adblock); detected when empty")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return exitError
+	}
+
+	code := exitOK
+	for _, file := range fs.Args() {
+		issues, domains, err := lintFile(*format, file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+			return exitError
+		}
+		for _, issue := range issues {
+			fmt.Printf("%s:%d: %s: %q\n", file, issue.Pos, issue.Problem, issue.Entry)
+		}
+		if len(issues) > 0 {
+			code = exitFindings
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d domains, %d issues\n", file, len(domains), len(issues))
+	}
+	return code
+}
+
+// runNormalize prints the normalized domains of a list, one per line. With
+// -check it prints nothing and exits 1 when the file is not already normalized.
+func runNormalize(args []string) int {
+	fs := newFlagSet("normalize", "<file>")
+	format := fs.String("format", "", "list format (txt, json, hosts, csv, adblock); detected when empty")
+	check := fs.Bool("check", false, "only report whether the file is already normalized")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return exitError
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return exitError
+	}
+	file := fs.Arg(0)
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	_, domains, err := service.LintList(withFormat(*format, file), data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", file, err)
+		return exitError
+	}
+
+	sorted := make([]string, 0, len(domains))
+	for d := range domains {
+		sorted = append(sorted, d)
+	}
+	sort.Strings(sorted)
+
+	var buf bytes.Buffer
+	for _, d := range sorted {
+		buf.WriteString(d)
+		buf.WriteByte('\n')
+	}
+
+	if *check {
+		if !bytes.Equal(buf.Bytes(), data) {
+			fmt.Fprintf(os.Stderr, "%s is not normalized\n", file)
+			return exitFindings
+		}
+		return exitOK
+	}
+
+	if *output != "" {
+		err = os.WriteFile(*output, buf.Bytes(), 0o644)
+	} else {
+		_, err = os.Stdout.Write(buf.Bytes())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return exitOK
+}
+
+// lintFile reads and lints a single list file
+func lintFile(format, file string) ([]service.LintIssue, map[string]bool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	return service.LintList(withFormat(format, file), data)
+}
+
+// withFormat prefixes a source with an explicit format, if any
+func withFormat(format, source string) string {
+	if format == "" {
+		return source
+	}
+	return format + "+" + source
+}
+
+// dash replaces an empty field with "-"
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -7,13 +7,18 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/config"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/handler"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/i18n"
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/logging"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/middleware"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/resolver"
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
 	appSentry "github.com/ilyasaftr/ory-kratos-disposable/pkg/sentry"
 )
@@ -36,8 +41,15 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
-// loggingMiddleware logs HTTP requests with their details
-func loggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+// Unwrap exposes the underlying writer to http.ResponseController, so
+// streaming handlers can flush and extend deadlines
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// loggingMiddleware logs HTTP requests with their details and records their
+// latency; routes are labelled with the mux pattern to bound cardinality
+func loggingMiddleware(logger *slog.Logger, mux *http.ServeMux) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -53,6 +65,14 @@ func loggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 
 			// Log request completion
 			duration := time.Since(start)
+			route := "unmatched"
+			if _, pattern := mux.Handler(r); pattern != "" {
+				route = pattern
+			}
+			metrics.HTTPRequestDuration.
+				WithLabelValues(r.Method, route, strconv.Itoa(rw.status)).
+				Observe(duration.Seconds())
+
 			logger.Info("request completed",
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
@@ -109,21 +129,121 @@ func main() {
 	logger.Info("starting ory kratos disposable email webhook",
 		slog.String("port", cfg.Server.Port),
 		slog.Duration("refresh_interval", cfg.Refresh.Interval),
-		slog.Int("list_urls_count", len(cfg.ListURLs)))
+		slog.Int("list_urls_count", len(cfg.ListURLs)),
+		slog.String("list_mode", cfg.ListMode))
 
 	// Create context for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Open the store of runtime-managed allow/deny rules
+	rules, err := service.OpenRuleStore(cfg.Rules.Path)
+	if err != nil {
+		logger.Error("failed to open custom rules store",
+			slog.String("path", cfg.Rules.Path),
+			slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Load the message catalogs; invalid catalogs abort startup
+	var catalog *i18n.Catalog
+	if cfg.Messages.CatalogDir != "" {
+		catalog, err = i18n.Load(cfg.Messages.CatalogDir, cfg.Messages.DefaultLocale)
+		if err != nil {
+			logger.Error("failed to load message catalogs",
+				slog.String("dir", cfg.Messages.CatalogDir),
+				slog.Any("error", err))
+			os.Exit(1)
+		}
+		logger.Info("message catalogs loaded",
+			slog.Any("locales", catalog.Locales()))
+	}
+
+	// Message IDs and types per outcome; an invalid scheme aborts startup
+	scheme, err := domain.NewMessageScheme(cfg.Messages.IDs, cfg.Messages.Types)
+	if err != nil {
+		logger.Error("invalid message scheme", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Local part checks; invalid patterns abort startup
+	localParts, err := service.NewLocalPartChecker(service.LocalPartOptions{
+		RoleAccounts:     cfg.LocalPart.RoleAccounts,
+		RandomMinEntropy: cfg.LocalPart.RandomMinEntropy,
+		RandomDigitRun:   cfg.LocalPart.RandomDigitRun,
+		Patterns:         cfg.LocalPart.Patterns,
+	})
+	if err != nil {
+		logger.Error("invalid local part checks", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	// Optional DNS checks
+	dnsOptions := service.DNSOptions{
+		RequireMX:         cfg.DNS.RequireMX,
+		DisposableMXHosts: cfg.DNS.DisposableMXHosts,
+	}
+	if cfg.DNS.Enabled {
+		dnsOptions.MXListURLs = cfg.DNS.MXListURLs
+		dnsOptions.NSListURLs = cfg.DNS.NSListURLs
+		dnsOptions.Resolver = resolver.NewCachingResolver(
+			resolver.NewNetResolver(cfg.DNS.Server, cfg.DNS.Timeout),
+			cfg.DNS.CacheTTL,
+			cfg.DNS.NegativeCacheTTL,
+			cfg.DNS.CacheSize,
+		)
+		logger.Info("dns checks enabled",
+			slog.String("server", cfg.DNS.Server),
+			slog.Duration("timeout", cfg.DNS.Timeout),
+			slog.Int("disposable_mx_hosts", len(cfg.DNS.DisposableMXHosts)),
+			slog.Int("mx_list_urls", len(cfg.DNS.MXListURLs)),
+			slog.Int("ns_list_urls", len(cfg.DNS.NSListURLs)))
+	} else if len(cfg.DNS.MXListURLs) > 0 || len(cfg.DNS.NSListURLs) > 0 {
+		logger.Warn("DNS_DISPOSABLE_MX_LIST_URLS and DNS_DISPOSABLE_NS_LIST_URLS are ignored while DNS_CHECKS_ENABLED is false")
+	}
+
+	// Local allow/deny overrides; an invalid inline entry aborts startup
+	overrides := service.Overrides{
+		AllowFiles:   cfg.Overrides.AllowFiles,
+		AllowDomains: cfg.Overrides.AllowDomains,
+		DenyFiles:    cfg.Overrides.DenyFiles,
+		DenyDomains:  cfg.Overrides.DenyDomains,
+	}
+	if err := overrides.Validate(); err != nil {
+		logger.Error("invalid local overrides", slog.Any("error", err))
+		os.Exit(1)
+	}
+
 	// Initialize disposable email service
 	disposableService := service.NewDisposableEmailService(
-		cfg.ListURLs,
-		cfg.Refresh.Interval,
+		service.Options{
+			ListURLs:         cfg.ListURLs,
+			ListMode:         cfg.ListMode,
+			RefreshInterval:  cfg.Refresh.Interval,
+			WatchInterval:    cfg.Refresh.WatchInterval,
+			SnapshotPath:     cfg.Refresh.SnapshotPath,
+			FailurePolicy:    cfg.Refresh.FailurePolicy,
+			GracePeriod:      cfg.Refresh.GracePeriod,
+			SyntaxMode:       cfg.Webhook.SyntaxMode,
+			DNS:              dnsOptions,
+			Overrides:        overrides,
+			Rules:            rules,
+			FreeMailListURLs: cfg.FreeMail.ListURLs,
+			LocalParts:       localParts,
+			Scoring: service.ScoreOptions{
+				Enabled:         cfg.Scoring.Enabled,
+				Weights:         cfg.Scoring.Weights,
+				FlagThreshold:   cfg.Scoring.FlagThreshold,
+				BlockThreshold:  cfg.Scoring.BlockThreshold,
+				NewDomainWindow: cfg.Scoring.NewDomainWindow,
+			},
+		},
 		logger,
 	)
 
 	// Start the service (load initial data and start auto-refresh)
-	// Note: Service always starts even if all URLs fail (fail mode)
+	// Note: Service always starts even if all URLs fail; DISPOSABLE_FAILURE_POLICY
+	// decides whether requests are allowed or rejected until a list loads
 	if err := disposableService.Start(ctx); err != nil {
 		// This should never happen since Start() always returns nil, but keep for safety
 		logger.Error("failed to start disposable email service", slog.Any("error", err))
@@ -131,8 +251,10 @@ func main() {
 	}
 
 	// Initialize handlers
-    validateHandler := handler.NewValidateHandler(disposableService, logger)
-    healthHandler := handler.NewHealthHandler(disposableService, logger)
+	validateHandler := handler.NewValidateHandler(disposableService, cfg.Webhook.EmailPointers, cfg.Webhook.LocalePointers, catalog, scheme, logger)
+	healthHandler := handler.NewHealthHandler(disposableService, cfg.Health.MaxStaleness, logger)
+	adminHandler := handler.NewAdminHandler(disposableService, logger)
+	batchHandler := handler.NewBatchHandler(disposableService, cfg.Batch.MaxItems, logger)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(cfg.Webhook.APIKey, logger)
@@ -140,11 +262,37 @@ func main() {
 	// Setup HTTP router
 	mux := http.NewServeMux()
 
-	// Health check endpoint (no auth required)
+	// Health check endpoints (no auth required)
+	// /health is kept for backward compatibility and reports readiness
 	mux.HandleFunc("/health", healthHandler.Handle)
-
-    // Validation endpoint (with auth)
-    mux.HandleFunc("/v1/validate/email", authMiddleware.Authenticate(validateHandler.Handle))
+	mux.HandleFunc("/health/live", healthHandler.Live)
+	mux.HandleFunc("/health/ready", healthHandler.Ready)
+
+	// Prometheus metrics endpoint (no auth required)
+	metrics.RegisterListGauges(disposableService)
+	mux.Handle("/metrics", metrics.Handler())
+
+	// Validation endpoint (with auth)
+	mux.HandleFunc("/v1/validate/email", authMiddleware.Authenticate(validateHandler.Handle))
+	mux.HandleFunc("/v1/validate/batch", authMiddleware.Authenticate(batchHandler.Handle))
+
+	// Admin endpoints use their own key and are not registered without one,
+	// so the key configured in the Kratos hooks never grants admin access
+	if cfg.Admin.APIKey != "" {
+		adminAuth := middleware.NewAuthMiddleware(cfg.Admin.APIKey, logger)
+		// Detailed health report exposes source URLs, which may carry tokens
+		mux.HandleFunc("/health/details", adminAuth.Authenticate(healthHandler.Details))
+		mux.HandleFunc("POST /v1/admin/refresh", adminAuth.Authenticate(adminHandler.Refresh))
+		mux.HandleFunc("GET /v1/admin/refresh/history", adminAuth.Authenticate(adminHandler.History))
+		mux.HandleFunc("GET /v1/admin/domains", adminAuth.Authenticate(adminHandler.ListDomains))
+		mux.HandleFunc("GET /v1/admin/domains/{domain}", adminAuth.Authenticate(adminHandler.LookupDomain))
+		mux.HandleFunc("GET /v1/admin/rules", adminAuth.Authenticate(adminHandler.ListRules))
+		mux.HandleFunc("GET /v1/admin/rules/{domain}", adminAuth.Authenticate(adminHandler.GetRule))
+		mux.HandleFunc("PUT /v1/admin/rules/{domain}", adminAuth.Authenticate(adminHandler.PutRule))
+		mux.HandleFunc("DELETE /v1/admin/rules/{domain}", adminAuth.Authenticate(adminHandler.DeleteRule))
+	} else {
+		logger.Info("admin API disabled - set ADMIN_API_KEY to enable it")
+	}
 
 	// Create HTTP handler with middleware chain
 	var handler http.Handler = mux
@@ -153,7 +301,7 @@ func main() {
 	handler = appSentry.HTTPMiddleware()(handler)
 
 	// Add request logging middleware
-	handler = loggingMiddleware(logger)(handler)
+	handler = loggingMiddleware(logger, mux)(handler)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -2,19 +2,33 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
 )
 
 type Config struct {
-	Server   ServerConfig
-	Webhook  WebhookConfig
-	Logger   LoggerConfig
-	Sentry   SentryConfig
-	ListURLs []string `env:"DISPOSABLE_LIST_URLS" envSeparator:"," envDefault:"https://cdn.jsdelivr.net/gh/ilyasaftr/disposable-email-domains@main/lists/deny.txt"`
-	Refresh  RefreshConfig
+	Server    ServerConfig
+	Webhook   WebhookConfig
+	Messages  MessagesConfig
+	Logger    LoggerConfig
+	Sentry    SentryConfig
+	ListURLs  []string `env:"DISPOSABLE_LIST_URLS" envSeparator:"," envDefault:"https://cdn.jsdelivr.net/gh/ilyasaftr/disposable-email-domains@main/lists/deny.txt"`
+	ListMode  string   `env:"DISPOSABLE_LIST_MODE" envDefault:"fallback"` // "fallback" (first success) or "union" (merge all)
+	Refresh   RefreshConfig
+	Overrides OverridesConfig
+	Health    HealthConfig
+	Admin     AdminConfig
+	Rules     RulesConfig
+	Batch     BatchConfig
+	DNS       DNSConfig
+	Scoring   ScoringConfig
+	FreeMail  FreeMailConfig
+	LocalPart LocalPartConfig
 }
 
 type ServerConfig struct {
@@ -22,7 +36,23 @@ type ServerConfig struct {
 }
 
 type WebhookConfig struct {
-	APIKey string `env:"WEBHOOK_API_KEY,required"`
+	APIKey     string `env:"WEBHOOK_API_KEY,required"`
+	SyntaxMode string `env:"EMAIL_SYNTAX_MODE" envDefault:"standard"` // "strict", "standard" or "lenient"
+	// JSON pointers locating the emails to check in the request body; every
+	// pointer that resolves is checked, and arrays are checked item by item.
+	// "/email" matches a custom {"email": "..."} body, "/identity/traits/email"
+	// the default Kratos web_hook context.
+	EmailPointers []string `env:"WEBHOOK_EMAIL_POINTERS" envSeparator:"," envDefault:"/email,/emails,/identity/traits/email,/identity/traits/emails"`
+	// JSON pointers to a locale such as "de" or "pt-BR" in the request body;
+	// the first that resolves wins over the Accept-Language headers
+	LocalePointers []string `env:"WEBHOOK_LOCALE_POINTERS" envSeparator:"," envDefault:"/locale,/identity/traits/locale"`
+}
+
+type MessagesConfig struct {
+	CatalogDir    string            `env:"MESSAGE_CATALOG_DIR"`                    // Directory of <locale>.json message catalogs (empty keeps the built-in English text)
+	DefaultLocale string            `env:"MESSAGE_DEFAULT_LOCALE" envDefault:"en"` // Locale used when no requested locale has a catalog
+	IDs           map[string]int    `env:"MESSAGE_IDS" envKeyValSeparator:"="`     // Message ID per outcome, e.g. "disposable=4100001,blocked=4100002"
+	Types         map[string]string `env:"MESSAGE_TYPES" envKeyValSeparator:"="`   // Message type ("error" or "info") per outcome, e.g. "undeliverable=info"
 }
 
 type LoggerConfig struct {
@@ -30,7 +60,75 @@ type LoggerConfig struct {
 }
 
 type RefreshConfig struct {
-	Interval time.Duration `env:"DISPOSABLE_LIST_UPDATE_INTERVAL" envDefault:"30m"`
+	Interval      time.Duration `env:"DISPOSABLE_LIST_UPDATE_INTERVAL" envDefault:"30m"`
+	WatchInterval time.Duration `env:"DISPOSABLE_FILE_WATCH_INTERVAL" envDefault:"5s"`  // Poll interval for local list files (0 disables)
+	SnapshotPath  string        `env:"DISPOSABLE_SNAPSHOT_PATH"`                        // Last good list for warm starts (empty disables)
+	FailurePolicy string        `env:"DISPOSABLE_FAILURE_POLICY" envDefault:"open"`     // "open", "closed" or "closed-after-grace"
+	GracePeriod   time.Duration `env:"DISPOSABLE_FAILURE_GRACE_PERIOD" envDefault:"5m"` // Startup grace for "closed-after-grace"
+}
+
+// OverridesConfig holds local allow/deny sources layered over the remote list.
+// Allow entries take precedence over deny entries and the remote list.
+type OverridesConfig struct {
+	AllowFiles   []string `env:"DISPOSABLE_ALLOW_FILES" envSeparator:","`   // Files with one domain per line
+	AllowDomains []string `env:"DISPOSABLE_ALLOW_DOMAINS" envSeparator:","` // Inline domains
+	DenyFiles    []string `env:"DISPOSABLE_DENY_FILES" envSeparator:","`
+	DenyDomains  []string `env:"DISPOSABLE_DENY_DOMAINS" envSeparator:","`
+}
+
+type AdminConfig struct {
+	APIKey string `env:"ADMIN_API_KEY"` // Key for /v1/admin endpoints; empty disables them
+}
+
+type RulesConfig struct {
+	Path string `env:"DISPOSABLE_RULES_PATH"` // JSON file persisting custom rules (empty keeps them in memory only)
+}
+
+// DNSConfig controls the optional MX and domain existence checks
+type DNSConfig struct {
+	Enabled           bool          `env:"DNS_CHECKS_ENABLED" envDefault:"false"`
+	Server            string        `env:"DNS_SERVER"`                                   // "host:port"; empty uses the system resolver
+	Timeout           time.Duration `env:"DNS_TIMEOUT" envDefault:"2s"`                  // Per lookup; timeouts skip the checks (fail open)
+	CacheTTL          time.Duration `env:"DNS_CACHE_TTL" envDefault:"10m"`               // How long answers are cached
+	NegativeCacheTTL  time.Duration `env:"DNS_NEGATIVE_CACHE_TTL" envDefault:"1m"`       // How long "not found" answers are cached
+	CacheSize         int           `env:"DNS_CACHE_SIZE" envDefault:"10000"`            // Max cached answers (0 disables caching)
+	RequireMX         bool          `env:"DNS_REQUIRE_MX" envDefault:"false"`            // Reject domains with address records but no MX
+	DisposableMXHosts []string      `env:"DNS_DISPOSABLE_MX_HOSTS" envSeparator:","`     // Known disposable mail exchangers
+	MXListURLs        []string      `env:"DNS_DISPOSABLE_MX_LIST_URLS" envSeparator:","` // Lists of disposable mail exchanger hosts, IPs and CIDR ranges
+	NSListURLs        []string      `env:"DNS_DISPOSABLE_NS_LIST_URLS" envSeparator:","` // Lists of disposable nameserver hosts, IPs and CIDR ranges
+}
+
+// FreeMailConfig holds the free mailbox provider lists used by hooks that
+// reject free mail (?reject=free_mail)
+type FreeMailConfig struct {
+	ListURLs []string `env:"FREE_MAIL_LIST_URLS" envSeparator:","` // Lists of free mailbox provider domains (empty uses the built-in providers)
+}
+
+// LocalPartConfig tunes the local part checks used by hooks that reject role
+// accounts, random local parts or pattern matches
+type LocalPartConfig struct {
+	RoleAccounts     []string `env:"LOCAL_PART_ROLE_ACCOUNTS" envSeparator:","`      // Role account names, e.g. "admin,noreply" (empty uses the built-in names)
+	Patterns         []string `env:"LOCAL_PART_PATTERNS" envSeparator:";"`           // Regular expressions matched against the whole local part, separated by ";" so quantifiers like "{1,3}" keep their comma
+	RandomMinEntropy float64  `env:"LOCAL_PART_RANDOM_MIN_ENTROPY" envDefault:"3.0"` // Letter entropy in bits from which letters with few vowels and many rare letters look random
+	RandomDigitRun   int      `env:"LOCAL_PART_RANDOM_DIGIT_RUN" envDefault:"0"`     // Length of a digit run that counts as random (0 disables)
+}
+
+// ScoringConfig controls the optional risk scoring, which replaces the binary
+// disposable verdict with a weighted score
+type ScoringConfig struct {
+	Enabled         bool           `env:"SCORING_ENABLED" envDefault:"false"`
+	Weights         map[string]int `env:"SCORE_WEIGHTS" envKeyValSeparator:"="`     // Signal weight overrides, e.g. "free_mail=20,new_domain=0"
+	FlagThreshold   int            `env:"SCORE_FLAG_THRESHOLD" envDefault:"30"`     // Score at which an address is flagged
+	BlockThreshold  int            `env:"SCORE_BLOCK_THRESHOLD" envDefault:"70"`    // Score at which the webhook rejects an address
+	NewDomainWindow time.Duration  `env:"SCORE_NEW_DOMAIN_WINDOW" envDefault:"24h"` // How long a first-seen domain counts as new (0 disables)
+}
+
+type BatchConfig struct {
+	MaxItems int `env:"BATCH_MAX_ITEMS" envDefault:"10000"` // Max emails per JSON batch request (NDJSON streams are not capped)
+}
+
+type HealthConfig struct {
+	MaxStaleness time.Duration `env:"HEALTH_MAX_STALENESS" envDefault:"0"` // Readiness fails when the list is older (0 disables)
 }
 
 type SentryConfig struct {
@@ -53,5 +151,49 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
 
+	switch cfg.ListMode {
+	case "fallback", "union":
+	default:
+		return nil, fmt.Errorf("invalid DISPOSABLE_LIST_MODE %q: must be \"fallback\" or \"union\"", cfg.ListMode)
+	}
+
+	switch cfg.Refresh.FailurePolicy {
+	case "open", "closed", "closed-after-grace":
+	default:
+		return nil, fmt.Errorf("invalid DISPOSABLE_FAILURE_POLICY %q: must be \"open\", \"closed\" or \"closed-after-grace\"", cfg.Refresh.FailurePolicy)
+	}
+
+	switch cfg.Webhook.SyntaxMode {
+	case "strict", "standard", "lenient":
+	default:
+		return nil, fmt.Errorf("invalid EMAIL_SYNTAX_MODE %q: must be \"strict\", \"standard\" or \"lenient\"", cfg.Webhook.SyntaxMode)
+	}
+
+	if cfg.DNS.Timeout <= 0 {
+		return nil, fmt.Errorf("invalid DNS_TIMEOUT %s: must be positive", cfg.DNS.Timeout)
+	}
+	if cfg.DNS.CacheSize < 0 {
+		return nil, fmt.Errorf("invalid DNS_CACHE_SIZE %d: must not be negative", cfg.DNS.CacheSize)
+	}
+
+	for signal, weight := range cfg.Scoring.Weights {
+		if _, ok := domain.DefaultSignalWeights[signal]; !ok {
+			return nil, fmt.Errorf("invalid SCORE_WEIGHTS: unknown signal %q (known: %s)", signal, strings.Join(domain.Signals(), ", "))
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("invalid SCORE_WEIGHTS: weight of %s must not be negative", signal)
+		}
+	}
+	if cfg.Scoring.FlagThreshold < 1 || cfg.Scoring.BlockThreshold < cfg.Scoring.FlagThreshold {
+		return nil, fmt.Errorf("invalid score thresholds: need 1 <= SCORE_FLAG_THRESHOLD (%d) <= SCORE_BLOCK_THRESHOLD (%d)", cfg.Scoring.FlagThreshold, cfg.Scoring.BlockThreshold)
+	}
+	if cfg.Scoring.NewDomainWindow < 0 {
+		return nil, fmt.Errorf("invalid SCORE_NEW_DOMAIN_WINDOW %s: must not be negative", cfg.Scoring.NewDomainWindow)
+	}
+
+	if cfg.Batch.MaxItems < 1 {
+		return nil, fmt.Errorf("invalid BATCH_MAX_ITEMS %d: must be at least 1", cfg.Batch.MaxItems)
+	}
+
 	return cfg, nil
 }
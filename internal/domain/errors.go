@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
 	// ErrInvalidEmail is returned when the email format is invalid
@@ -8,4 +11,50 @@ var (
 
 	// ErrMissingEmail is returned when the email is not provided
 	ErrMissingEmail = errors.New("email is required")
+
+	// ErrListUnavailable is returned when no list has loaded yet and the
+	// failure policy rejects requests
+	ErrListUnavailable = errors.New("disposable domains list unavailable")
+)
+
+// Syntax error reasons reported for malformed email addresses
+const (
+	SyntaxEmpty               = "empty"
+	SyntaxTooLong             = "too_long"
+	SyntaxMissingAt           = "missing_at"
+	SyntaxMultipleAt          = "multiple_at"
+	SyntaxEmptyLocalPart      = "empty_local_part"
+	SyntaxLocalPartTooLong    = "local_part_too_long"
+	SyntaxLocalPartDots       = "local_part_dots"
+	SyntaxLocalPartCharacters = "local_part_characters"
+	SyntaxUnterminatedQuote   = "unterminated_quote"
+	SyntaxQuotedNotAllowed    = "quoted_not_allowed"
+	SyntaxComment             = "comment"
+	SyntaxEmptyDomain         = "empty_domain"
+	SyntaxInvalidDomain       = "invalid_domain"
+	SyntaxDomainNotQualified  = "domain_not_qualified"
+	SyntaxIPLiteralNotAllowed = "ip_literal_not_allowed"
+	SyntaxInvalidIPLiteral    = "invalid_ip_literal"
 )
+
+// SyntaxError reports why an email address is malformed. It matches
+// ErrInvalidEmail with errors.Is.
+type SyntaxError struct {
+	// Reason is one of the Syntax* constants
+	Reason string
+	// Detail adds specifics such as the offending character; may be empty
+	Detail string
+}
+
+func (e *SyntaxError) Error() string {
+	msg := ErrInvalidEmail.Error() + ": " + strings.ReplaceAll(e.Reason, "_", " ")
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrInvalidEmail) hold for syntax errors
+func (e *SyntaxError) Is(target error) bool {
+	return target == ErrInvalidEmail
+}
@@ -0,0 +1,163 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Outcomes name the reasons the webhook reports an address for. Each has a
+// default message ID; syntax errors are named "syntax.<reason>", e.g.
+// "syntax.missing_at", and local part findings by their LocalPart* name.
+const (
+	OutcomeDisposable    = "disposable"
+	OutcomeBlocked       = "blocked"
+	OutcomeRisky         = "risky"
+	OutcomeFreeMail      = "free_mail"
+	OutcomeUnavailable   = "unavailable"
+	OutcomeNoDomain      = "no_domain"
+	OutcomeUndeliverable = "undeliverable"
+	OutcomeInvalidEmail  = "invalid_email"
+)
+
+// errorOnlyOutcomes always reject the address: an "info" message would
+// answer HTTP 200 and let a disposable or blocked address through
+var errorOnlyOutcomes = map[string]bool{
+	OutcomeDisposable: true,
+	OutcomeBlocked:    true,
+}
+
+// syntaxOutcomePrefix prefixes the syntax error reasons in outcome names
+const syntaxOutcomePrefix = "syntax."
+
+// outcomeIDs maps the outcomes other than syntax errors to their default ID
+var outcomeIDs = map[string]int{
+	OutcomeDisposable:    MessageIDDisposable,
+	OutcomeBlocked:       MessageIDBlocked,
+	OutcomeRisky:         MessageIDRisky,
+	OutcomeFreeMail:      MessageIDFreeMail,
+	LocalPartRoleAccount: MessageIDRoleAccount,
+	LocalPartRandom:      MessageIDRandomLocalPart,
+	LocalPartPattern:     MessageIDLocalPartPattern,
+	OutcomeUnavailable:   MessageIDUnavailable,
+	OutcomeNoDomain:      MessageIDNoDomain,
+	OutcomeUndeliverable: MessageIDUndeliverable,
+	OutcomeInvalidEmail:  MessageIDInvalidEmail,
+}
+
+// OutcomeMessageIDs returns every outcome with its default message ID
+func OutcomeMessageIDs() map[string]int {
+	ids := make(map[string]int, len(outcomeIDs)+len(syntaxMessages))
+	for outcome, id := range outcomeIDs {
+		ids[outcome] = id
+	}
+	for reason, msg := range syntaxMessages {
+		ids[syntaxOutcomePrefix+reason] = msg.ID
+	}
+	return ids
+}
+
+// Outcomes returns the sorted outcome names
+func Outcomes() []string {
+	ids := OutcomeMessageIDs()
+	outcomes := make([]string, 0, len(ids))
+	for outcome := range ids {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+	return outcomes
+}
+
+// MessageScheme assigns the ID and type of the message sent for each
+// outcome. The zero value keeps the default IDs and the "error" type.
+type MessageScheme struct {
+	// ids maps a default message ID to the configured one
+	ids map[int]int
+	// types maps a default message ID to the configured type
+	types map[int]string
+}
+
+// NewMessageScheme builds a scheme from per-outcome ID and type overrides,
+// both keyed by outcome name. It rejects unknown outcomes, non-positive
+// IDs, IDs shared by two outcomes, types other than "error" and "info", and
+// the "info" type for the disposable and blocked outcomes.
+func NewMessageScheme(ids map[string]int, types map[string]string) (MessageScheme, error) {
+	defaults := OutcomeMessageIDs()
+	scheme := MessageScheme{
+		ids:   make(map[int]int, len(ids)),
+		types: make(map[int]string, len(types)),
+	}
+
+	for outcome, id := range ids {
+		def, ok := defaults[outcome]
+		if !ok {
+			return MessageScheme{}, fmt.Errorf("unknown outcome %q in message IDs (known: %s)", outcome, strings.Join(Outcomes(), ", "))
+		}
+		if id <= 0 {
+			return MessageScheme{}, fmt.Errorf("invalid message ID %d for outcome %s", id, outcome)
+		}
+		scheme.ids[def] = id
+	}
+
+	// Every outcome must still have its own ID after remapping
+	owners := make(map[int]string, len(defaults))
+	for _, outcome := range Outcomes() {
+		id := scheme.id(defaults[outcome])
+		if other, taken := owners[id]; taken {
+			return MessageScheme{}, fmt.Errorf("message ID %d is used by both %s and %s", id, other, outcome)
+		}
+		owners[id] = outcome
+	}
+
+	for outcome, typ := range types {
+		def, ok := defaults[outcome]
+		if !ok {
+			return MessageScheme{}, fmt.Errorf("unknown outcome %q in message types (known: %s)", outcome, strings.Join(Outcomes(), ", "))
+		}
+		typ = strings.ToLower(strings.TrimSpace(typ))
+		if typ != MessageTypeError && typ != MessageTypeInfo {
+			return MessageScheme{}, fmt.Errorf("invalid message type %q for outcome %s (must be %q or %q)", typ, outcome, MessageTypeError, MessageTypeInfo)
+		}
+		if typ == MessageTypeInfo && errorOnlyOutcomes[outcome] {
+			return MessageScheme{}, fmt.Errorf("outcome %s always rejects the address and cannot use the %q type", outcome, MessageTypeInfo)
+		}
+		scheme.types[def] = typ
+	}
+
+	return scheme, nil
+}
+
+// id returns the configured ID for a default message ID
+func (s MessageScheme) id(def int) int {
+	if id, ok := s.ids[def]; ok {
+		return id
+	}
+	return def
+}
+
+// Apply sets the configured ID and type of every message in groups, which
+// must still carry their default IDs
+func (s MessageScheme) Apply(groups []MessageGroup) {
+	for i := range groups {
+		for j := range groups[i].Messages {
+			msg := &groups[i].Messages[j]
+			if typ, ok := s.types[msg.ID]; ok {
+				msg.Type = typ
+			}
+			msg.ID = s.id(msg.ID)
+		}
+	}
+}
+
+// Blocking reports whether any message in groups is an error; groups with
+// only "info" messages do not interrupt the flow
+func Blocking(groups []MessageGroup) bool {
+	for _, group := range groups {
+		for _, msg := range group.Messages {
+			if msg.Type == MessageTypeError {
+				return true
+			}
+		}
+	}
+	return false
+}
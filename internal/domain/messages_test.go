@@ -0,0 +1,103 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMessageScheme(t *testing.T) {
+	scheme, err := NewMessageScheme(
+		map[string]int{OutcomeDisposable: 1001, "syntax.missing_at": 1002},
+		map[string]string{OutcomeFreeMail: " INFO ", OutcomeDisposable: "error"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missingAt := OutcomeMessageIDs()["syntax.missing_at"]
+	groups := []MessageGroup{{Messages: []Message{
+		{ID: MessageIDDisposable, Type: MessageTypeError},
+		{ID: missingAt, Type: MessageTypeError},
+		{ID: MessageIDFreeMail, Type: MessageTypeError},
+		{ID: MessageIDUndeliverable, Type: MessageTypeError},
+	}}}
+	scheme.Apply(groups)
+
+	tests := []struct {
+		name     string
+		wantID   int
+		wantType string
+	}{
+		{"remapped", 1001, MessageTypeError},
+		{"remapped syntax reason", 1002, MessageTypeError},
+		{"info type", MessageIDFreeMail, MessageTypeInfo},
+		{"unchanged", MessageIDUndeliverable, MessageTypeError},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := groups[0].Messages[i]
+			if msg.ID != tt.wantID || msg.Type != tt.wantType {
+				t.Errorf("message = %d/%s, want %d/%s", msg.ID, msg.Type, tt.wantID, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestNewMessageSchemeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ids     map[string]int
+		types   map[string]string
+		wantErr string
+	}{
+		{"unknown outcome ID", map[string]int{"spam": 1}, nil, `unknown outcome "spam" in message IDs`},
+		{"zero ID", map[string]int{OutcomeDisposable: 0}, nil, "invalid message ID 0"},
+		{"negative ID", map[string]int{OutcomeDisposable: -1}, nil, "invalid message ID -1"},
+		{"shared ID", map[string]int{OutcomeDisposable: 7, OutcomeFreeMail: 7}, nil, "message ID 7 is used by both"},
+		{"ID of another outcome", map[string]int{OutcomeDisposable: MessageIDFreeMail}, nil, "is used by both"},
+		{"unknown outcome type", nil, map[string]string{"spam": MessageTypeInfo}, `unknown outcome "spam" in message types`},
+		{"invalid type", nil, map[string]string{OutcomeDisposable: "warning"}, `invalid message type "warning"`},
+		{"info for disposable", nil, map[string]string{OutcomeDisposable: "Info"}, `outcome disposable always rejects`},
+		{"info for blocked", nil, map[string]string{OutcomeBlocked: MessageTypeInfo}, `outcome blocked always rejects`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewMessageScheme(tt.ids, tt.types)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewMessageScheme() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMessageSchemeSwap(t *testing.T) {
+	// Swapping two default IDs leaves every outcome with its own ID
+	if _, err := NewMessageScheme(map[string]int{
+		OutcomeDisposable: MessageIDFreeMail,
+		OutcomeFreeMail:   MessageIDDisposable,
+	}, nil); err != nil {
+		t.Errorf("NewMessageScheme() with swapped IDs: %v", err)
+	}
+}
+
+func TestBlocking(t *testing.T) {
+	tests := []struct {
+		name   string
+		groups []MessageGroup
+		want   bool
+	}{
+		{"none", nil, false},
+		{"info only", []MessageGroup{{Messages: []Message{{Type: MessageTypeInfo}}}}, false},
+		{"error", []MessageGroup{
+			{Messages: []Message{{Type: MessageTypeInfo}}},
+			{Messages: []Message{{Type: MessageTypeError}}},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Blocking(tt.groups); got != tt.want {
+				t.Errorf("Blocking() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
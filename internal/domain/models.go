@@ -1,5 +1,11 @@
 package domain
 
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
 // OryWebhookResponse represents the response to send back to Ory Kratos
 type OryWebhookResponse struct {
 	Messages []MessageGroup `json:"messages,omitempty"`
@@ -19,24 +25,400 @@ type Message struct {
 	Context map[string]interface{} `json:"context,omitempty"`
 }
 
-// NewErrorResponse creates an error response for disposable email
-func NewErrorResponse(email, domain string) OryWebhookResponse {
+// Default message IDs of the webhook outcomes (see Outcome*); syntax errors
+// use the IDs in syntaxMessages. Operators can remap them with a
+// MessageScheme.
+const (
+	MessageIDDisposable    = 4000001
+	MessageIDUnavailable   = 4000002
+	MessageIDNoDomain      = 4000003
+	MessageIDUndeliverable = 4000004
+	MessageIDBlocked       = 4000005
+	MessageIDRisky         = 4000006
+	MessageIDFreeMail      = 4000007
+	MessageIDInvalidEmail  = 4000009
+	// Local part checks
+	MessageIDRoleAccount      = 4000030
+	MessageIDRandomLocalPart  = 4000031
+	MessageIDLocalPartPattern = 4000032
+)
+
+// Message types understood by Ory
+const (
+	MessageTypeError = "error"
+	MessageTypeInfo  = "info"
+)
+
+// requestErrorIDBase is added to the HTTP status of request errors (bad body,
+// missing email, authentication) to form their message ID, keeping them
+// apart from both the outcome IDs and the IDs used by Kratos itself
+const requestErrorIDBase = 4900000
+
+// RequestErrorID returns the message ID of a request error answered with
+// statusCode, e.g. 4900400 for a malformed body
+func RequestErrorID(statusCode int) int {
+	return requestErrorIDBase + statusCode
+}
+
+// NewRequestErrorResponse creates the response to a request error answered
+// with statusCode, with ID RequestErrorID(statusCode)
+func NewRequestErrorResponse(statusCode int, message string) OryWebhookResponse {
 	return OryWebhookResponse{
 		Messages: []MessageGroup{
 			{
-				InstancePtr: "#/traits/email",
+				InstancePtr: "#/",
 				Messages: []Message{
 					{
-						ID:   4000001,
-						Text: "Disposable email addresses are not allowed",
-						Type: "error",
-						Context: map[string]interface{}{
-							"email":  email,
-							"domain": domain,
-						},
+						ID:   RequestErrorID(statusCode),
+						Text: message,
+						Type: MessageTypeError,
 					},
 				},
 			},
 		},
 	}
 }
+
+// MessageContextKeys are every key a message context may contain
+var MessageContextKeys = []string{
+	"email", "domain", "domain_unicode", "matched_domain", "matched_rule",
+	"source", "mx_host", "ns_host", "matched_ip", "reason", "detail",
+	"score", "signals", "local_part",
+}
+
+// MessageIDs returns the ID of every message the webhook can send
+func MessageIDs() []int {
+	ids := make([]int, 0, len(syntaxMessages)+len(outcomeIDs))
+	for _, id := range OutcomeMessageIDs() {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// Verdict sources describe which input decided a verdict
+const (
+	SourceCustomAllow = "custom_allow"
+	SourceCustomDeny  = "custom_deny"
+	SourceAllowlist   = "allowlist"
+	SourceDenylist    = "denylist"
+	SourceList        = "list"
+	SourceMX          = "mx"
+	SourceNS          = "ns"
+	// SourceFreeMail is reported in free-mail messages; it never decides a
+	// verdict
+	SourceFreeMail = "free_mail"
+)
+
+// Undeliverable reasons reported by the DNS checks
+const (
+	// UndeliverableNoDomain means the domain does not exist (NXDOMAIN)
+	UndeliverableNoDomain = "no_domain"
+	// UndeliverableNullMX means the domain declares that it accepts no email
+	// with a "." MX record (RFC 7505)
+	UndeliverableNullMX = "null_mx"
+	// UndeliverableNoMX means the domain exists but has no MX record, and
+	// either has no address records either or falling back to them is disabled
+	UndeliverableNoMX = "no_mx"
+)
+
+// Verdict is the outcome of checking a single email address
+type Verdict struct {
+	Email string
+	// Domain is the canonical ASCII (punycode) form of the email domain
+	Domain string
+	// DomainUnicode is the Unicode form of Domain, equal to it for plain
+	// ASCII domains
+	DomainUnicode string
+	Disposable    bool
+	// MatchedDomain is the listed domain that matched; it is a parent of
+	// Domain when the address uses a subdomain of a disposable provider.
+	MatchedDomain string
+	// MatchedRule is the wildcard or regular expression list entry that
+	// matched MatchedDomain; empty for exact matches
+	MatchedRule string
+	// Source is the kind of input that decided the verdict (one of the
+	// Source* constants); empty when nothing matched.
+	Source string
+	// Origin identifies the concrete input: a file path, an environment
+	// variable name, a list URL or the author of a custom rule.
+	Origin string
+	// FailOpen is set when the address was allowed only because no list has
+	// loaded yet
+	FailOpen bool
+	// MXHost is the mail exchanger of Domain that matched a disposable mail
+	// exchanger entry (Source is SourceMX)
+	MXHost string
+	// NSHost is the nameserver of Domain that matched a disposable
+	// nameserver entry (Source is SourceNS)
+	NSHost string
+	// MatchedIP is the address of MXHost or NSHost that fell within a listed
+	// IP range; MatchedDomain is then the range
+	MatchedIP string
+	// Undeliverable is one of the Undeliverable* reasons when the DNS checks
+	// found that Domain cannot receive email
+	Undeliverable string
+	// FreeMail is the free mailbox provider entry matching Domain (or a
+	// parent of it); empty for other domains
+	FreeMail string
+	// FreeMailOrigin is the free-mail list URL that listed FreeMail, or
+	// "builtin" for the built-in providers
+	FreeMailOrigin string
+	// RoleAccount is the role account name the local part matched, e.g.
+	// "admin" for "Admin+x@example.com"
+	RoleAccount string
+	// RandomLocalPart describes why the local part looks randomly generated
+	RandomLocalPart string
+	// LocalPartPattern is the configured local part pattern that matched
+	LocalPartPattern string
+	// NoMX is set when the DNS checks found no usable MX record for Domain,
+	// even if it can still receive email at its address records
+	NoMX bool
+	// Risk is the scored assessment; nil unless risk scoring is enabled
+	Risk *Risk
+}
+
+// NewDisposableMessageGroup creates the message group for a disposable email.
+// instancePtr points at the offending identity field, e.g. "#/traits/email".
+// Addresses rejected by a local denylist or a custom deny rule get the
+// "blocked by policy" message instead.
+func NewDisposableMessageGroup(instancePtr string, v Verdict) MessageGroup {
+	group := MessageGroup{
+		InstancePtr: instancePtr,
+		Messages: []Message{
+			{
+				ID:   MessageIDDisposable,
+				Text: "Disposable email addresses are not allowed",
+				Type: MessageTypeError,
+				Context: map[string]interface{}{
+					"email":          v.Email,
+					"domain":         v.Domain,
+					"domain_unicode": v.DomainUnicode,
+					"matched_domain": v.MatchedDomain,
+					"source":         v.Source,
+				},
+			},
+		},
+	}
+	if v.Source == SourceDenylist || v.Source == SourceCustomDeny {
+		group.Messages[0].ID = MessageIDBlocked
+		group.Messages[0].Text = "This email address is not allowed"
+	}
+	if v.MatchedRule != "" {
+		group.Messages[0].Context["matched_rule"] = v.MatchedRule
+	}
+	if v.MXHost != "" {
+		group.Messages[0].Context["mx_host"] = v.MXHost
+	}
+	if v.NSHost != "" {
+		group.Messages[0].Context["ns_host"] = v.NSHost
+	}
+	if v.MatchedIP != "" {
+		group.Messages[0].Context["matched_ip"] = v.MatchedIP
+	}
+	return group
+}
+
+// NewUndeliverableMessageGroup creates the message group for a domain that
+// the DNS checks found unable to receive email
+func NewUndeliverableMessageGroup(instancePtr string, v Verdict) MessageGroup {
+	msg := Message{
+		ID:   MessageIDUndeliverable,
+		Text: "The email domain cannot receive email",
+		Type: MessageTypeError,
+		Context: map[string]interface{}{
+			"email":  v.Email,
+			"domain": v.Domain,
+			"reason": v.Undeliverable,
+		},
+	}
+	if v.Undeliverable == UndeliverableNoDomain {
+		msg.ID = MessageIDNoDomain
+		msg.Text = "The email domain does not exist"
+	}
+
+	return MessageGroup{
+		InstancePtr: instancePtr,
+		Messages:    []Message{msg},
+	}
+}
+
+// NewFreeMailMessageGroup creates the message group for an address at a free
+// mailbox provider, on hooks that reject them
+func NewFreeMailMessageGroup(instancePtr string, v Verdict) MessageGroup {
+	return MessageGroup{
+		InstancePtr: instancePtr,
+		Messages: []Message{
+			{
+				ID:   MessageIDFreeMail,
+				Text: "Please use your work email address, free email providers are not allowed",
+				Type: MessageTypeError,
+				Context: map[string]interface{}{
+					"email":          v.Email,
+					"domain":         v.Domain,
+					"domain_unicode": v.DomainUnicode,
+					"matched_domain": v.FreeMail,
+					"source":         SourceFreeMail,
+				},
+			},
+		},
+	}
+}
+
+// Local part findings, used as the reason in local part messages
+const (
+	LocalPartRoleAccount = "role_account"
+	LocalPartRandom      = "random_local_part"
+	LocalPartPattern     = "local_part_pattern"
+)
+
+// localPartMessages maps each local part finding to its message
+var localPartMessages = map[string]syntaxMessage{
+	LocalPartRoleAccount: {MessageIDRoleAccount, "Role addresses such as admin@ or info@ are not allowed, please use a personal address"},
+	LocalPartRandom:      {MessageIDRandomLocalPart, "This email address looks randomly generated, please use a different one"},
+	LocalPartPattern:     {MessageIDLocalPartPattern, "This email address is not allowed, please use a different one"},
+}
+
+// NewLocalPartMessageGroup creates the message group for a local part
+// finding (one of the LocalPart* constants), on hooks that reject it. The
+// context carries the finding as reason and what matched as detail.
+func NewLocalPartMessageGroup(instancePtr string, v Verdict, finding string) MessageGroup {
+	detail := map[string]string{
+		LocalPartRoleAccount: v.RoleAccount,
+		LocalPartRandom:      v.RandomLocalPart,
+		LocalPartPattern:     v.LocalPartPattern,
+	}[finding]
+	known := localPartMessages[finding]
+	local := v.Email
+	if i := strings.LastIndexByte(local, '@'); i >= 0 {
+		local = local[:i]
+	}
+
+	return MessageGroup{
+		InstancePtr: instancePtr,
+		Messages: []Message{
+			{
+				ID:   known.ID,
+				Text: known.Text,
+				Type: MessageTypeError,
+				Context: map[string]interface{}{
+					"email":      v.Email,
+					"domain":     v.Domain,
+					"local_part": local,
+					"reason":     finding,
+					"detail":     detail,
+				},
+			},
+		},
+	}
+}
+
+// NewRiskMessageGroup creates the message group for an address whose risk
+// score reached the block threshold: the disposable or undeliverable message
+// when one of those applies, the generic risky address message otherwise.
+// The score and the names of the signals are added to the context.
+func NewRiskMessageGroup(instancePtr string, v Verdict) MessageGroup {
+	var group MessageGroup
+	switch {
+	case v.Disposable:
+		group = NewDisposableMessageGroup(instancePtr, v)
+	case v.Undeliverable != "":
+		group = NewUndeliverableMessageGroup(instancePtr, v)
+	default:
+		group = MessageGroup{
+			InstancePtr: instancePtr,
+			Messages: []Message{
+				{
+					ID:   MessageIDRisky,
+					Text: "This email address cannot be used, please use a different one",
+					Type: MessageTypeError,
+					Context: map[string]interface{}{
+						"email":  v.Email,
+						"domain": v.Domain,
+					},
+				},
+			},
+		}
+	}
+	if v.Risk != nil {
+		group.Messages[0].Context["score"] = v.Risk.Score
+		group.Messages[0].Context["signals"] = strings.Join(v.Risk.SignalNames(), ",")
+	}
+	return group
+}
+
+// NewUnavailableMessageGroup creates the message group for when the list is
+// not loaded and the failure policy rejects the request
+func NewUnavailableMessageGroup(instancePtr, email string) MessageGroup {
+	return MessageGroup{
+		InstancePtr: instancePtr,
+		Messages: []Message{
+			{
+				ID:   MessageIDUnavailable,
+				Text: "Email validation is temporarily unavailable, please try again later",
+				Type: MessageTypeError,
+				Context: map[string]interface{}{
+					"email": email,
+				},
+			},
+		},
+	}
+}
+
+// syntaxMessage is the Ory message shown for a syntax error reason or a
+// local part finding
+type syntaxMessage struct {
+	ID   int
+	Text string
+}
+
+// syntaxMessages maps each Syntax* reason to its message
+var syntaxMessages = map[string]syntaxMessage{
+	SyntaxEmpty:               {4000010, "Please enter an email address"},
+	SyntaxTooLong:             {4000011, "Email address is too long (at most 254 characters)"},
+	SyntaxMissingAt:           {4000012, "Email address must contain an \"@\" sign"},
+	SyntaxMultipleAt:          {4000013, "Email address must contain a single \"@\" sign"},
+	SyntaxEmptyLocalPart:      {4000014, "Email address is missing the part before the \"@\" sign"},
+	SyntaxLocalPartTooLong:    {4000015, "The part before the \"@\" sign is too long (at most 64 characters)"},
+	SyntaxLocalPartDots:       {4000016, "The part before the \"@\" sign cannot start or end with a dot or contain consecutive dots"},
+	SyntaxLocalPartCharacters: {4000017, "The part before the \"@\" sign contains characters that are not allowed"},
+	SyntaxUnterminatedQuote:   {4000018, "Email address contains an unterminated quoted string"},
+	SyntaxQuotedNotAllowed:    {4000019, "Quoted email addresses are not allowed"},
+	SyntaxComment:             {4000020, "Comments are not allowed in email addresses"},
+	SyntaxEmptyDomain:         {4000021, "Email address is missing the domain after the \"@\" sign"},
+	SyntaxInvalidDomain:       {4000022, "The domain of the email address is not valid"},
+	SyntaxDomainNotQualified:  {4000023, "The domain of the email address must contain a dot"},
+	SyntaxIPLiteralNotAllowed: {4000024, "Email addresses with an IP address instead of a domain are not allowed"},
+	SyntaxInvalidIPLiteral:    {4000025, "The IP address in the email address is not valid"},
+}
+
+// NewInvalidEmailMessageGroup creates the message group for a malformed email.
+// A *SyntaxError selects the message for its reason; any other error gets the
+// generic "Invalid email format" message.
+func NewInvalidEmailMessageGroup(instancePtr, email string, err error) MessageGroup {
+	msg := Message{
+		ID:   MessageIDInvalidEmail,
+		Text: "Invalid email format",
+		Type: MessageTypeError,
+		Context: map[string]interface{}{
+			"email": email,
+		},
+	}
+
+	var syntaxErr *SyntaxError
+	if errors.As(err, &syntaxErr) {
+		if known, ok := syntaxMessages[syntaxErr.Reason]; ok {
+			msg.ID = known.ID
+			msg.Text = known.Text
+		}
+		msg.Context["reason"] = syntaxErr.Reason
+		if syntaxErr.Detail != "" {
+			msg.Context["detail"] = syntaxErr.Detail
+		}
+	}
+
+	return MessageGroup{
+		InstancePtr: instancePtr,
+		Messages:    []Message{msg},
+	}
+}
@@ -0,0 +1,80 @@
+package domain
+
+import "sort"
+
+// Risk signals combined into a score when risk scoring is enabled
+const (
+	// SignalListHit: the domain itself is on a disposable list
+	SignalListHit = "list_hit"
+	// SignalSubdomainHit: a parent of the domain is on a disposable list
+	SignalSubdomainHit = "subdomain_hit"
+	// SignalHostHit: a mail exchanger or nameserver of the domain is listed
+	SignalHostHit = "host_hit"
+	// SignalNoMX: the domain has no usable MX record (requires DNS checks)
+	SignalNoMX = "no_mx"
+	// SignalNewDomain: the domain was first seen recently
+	SignalNewDomain = "new_domain"
+	// SignalFreeMail: the domain belongs to a free mailbox provider
+	SignalFreeMail = "free_mail"
+	// SignalRoleAccount: the local part is a role account such as "admin"
+	SignalRoleAccount = "role_account"
+	// SignalSuspiciousLocalPart: the local part looks machine-generated
+	SignalSuspiciousLocalPart = "suspicious_local_part"
+)
+
+// DefaultSignalWeights are the weights used for signals without a
+// configured weight
+var DefaultSignalWeights = map[string]int{
+	SignalListHit:             100,
+	SignalSubdomainHit:        80,
+	SignalHostHit:             80,
+	SignalNoMX:                60,
+	SignalNewDomain:           20,
+	SignalFreeMail:            10,
+	SignalRoleAccount:         30,
+	SignalSuspiciousLocalPart: 30,
+}
+
+// Signals returns the sorted signal names
+func Signals() []string {
+	signals := make([]string, 0, len(DefaultSignalWeights))
+	for signal := range DefaultSignalWeights {
+		signals = append(signals, signal)
+	}
+	sort.Strings(signals)
+	return signals
+}
+
+// Risk decisions, from the score and the configured thresholds
+const (
+	RiskAllow = "allow"
+	RiskFlag  = "flag"
+	RiskBlock = "block"
+)
+
+// Signal is a risk signal that fired for an address
+type Signal struct {
+	Name   string `json:"name"`
+	Weight int    `json:"weight"`
+	// Detail is what triggered the signal, e.g. the matched domain
+	Detail string `json:"detail,omitempty"`
+}
+
+// Risk is the scored assessment of an address
+type Risk struct {
+	Score    int      `json:"score"`
+	Decision string   `json:"decision"`
+	Signals  []Signal `json:"signals"`
+	// Policy is set when an allow or deny entry decided the address
+	// regardless of the score
+	Policy bool `json:"policy,omitempty"`
+}
+
+// SignalNames returns the names of the signals that fired, in order
+func (r *Risk) SignalNames() []string {
+	names := make([]string, 0, len(r.Signals))
+	for _, signal := range r.Signals {
+		names = append(names, signal.Name)
+	}
+	return names
+}
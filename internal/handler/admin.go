@@ -0,0 +1,256 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+// Paging limits for the domain listing
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// AdminHandler exposes operator endpoints to inspect and refresh the list
+type AdminHandler struct {
+	disposableService *service.DisposableEmailService
+	logger            *slog.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(svc *service.DisposableEmailService, log *slog.Logger) *AdminHandler {
+	return &AdminHandler{
+		disposableService: svc,
+		logger:            log,
+	}
+}
+
+// LookupResponse explains whether a domain matches and why
+type LookupResponse struct {
+	Domain        string `json:"domain"`
+	DomainUnicode string `json:"domain_unicode"`
+	Disposable    bool   `json:"disposable"`
+	MatchedDomain string `json:"matched_domain,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Origin        string `json:"origin,omitempty"`
+	Reason        string `json:"reason"`
+	// FreeMail is the matching free mailbox provider entry
+	FreeMail string `json:"free_mail,omitempty"`
+}
+
+// DomainsResponse is a page of loaded domains
+type DomainsResponse struct {
+	Total   int                   `json:"total"`
+	Offset  int                   `json:"offset"`
+	Limit   int                   `json:"limit"`
+	Domains []service.DomainEntry `json:"domains"`
+}
+
+// HistoryResponse lists recent refreshes, newest first
+type HistoryResponse struct {
+	Refreshes []service.RefreshReport `json:"refreshes"`
+}
+
+// Refresh runs a refresh synchronously and returns the per-URL outcome
+func (h *AdminHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("manual refresh requested", slog.String("ip", r.RemoteAddr))
+
+	report, err := h.disposableService.Refresh()
+	status := http.StatusOK
+	if err != nil {
+		// The report carries the per-URL errors
+		status = http.StatusBadGateway
+	}
+	RespondJSON(w, h.logger, status, report)
+}
+
+// History returns the recent refresh reports
+func (h *AdminHandler) History(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, h.logger, http.StatusOK, HistoryResponse{
+		Refreshes: h.disposableService.RefreshHistory(),
+	})
+}
+
+// LookupDomain reports whether a domain matches and the reason
+func (h *AdminHandler) LookupDomain(w http.ResponseWriter, r *http.Request) {
+	verdict, err := h.disposableService.Lookup(r.PathValue("domain"))
+	if err != nil {
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid domain")
+		return
+	}
+
+	RespondJSON(w, h.logger, http.StatusOK, LookupResponse{
+		Domain:        verdict.Domain,
+		DomainUnicode: verdict.DomainUnicode,
+		Disposable:    verdict.Disposable,
+		MatchedDomain: verdict.MatchedDomain,
+		Source:        verdict.Source,
+		Origin:        verdict.Origin,
+		Reason:        explainVerdict(verdict),
+		FreeMail:      verdict.FreeMail,
+	})
+}
+
+// ListDomains pages through the loaded domains (?offset=0&limit=100)
+func (h *AdminHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid offset")
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultPageLimit)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		RespondError(w, h.logger, http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", maxPageLimit))
+		return
+	}
+
+	domains, total := h.disposableService.Domains(offset, limit)
+	RespondJSON(w, h.logger, http.StatusOK, DomainsResponse{
+		Total:   total,
+		Offset:  offset,
+		Limit:   limit,
+		Domains: domains,
+	})
+}
+
+// RuleRequest is the body of PUT /v1/admin/rules/{domain}
+type RuleRequest struct {
+	Action    string     `json:"action"`
+	Author    string     `json:"author"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// RulesResponse lists the active custom rules
+type RulesResponse struct {
+	Rules []service.Rule `json:"rules"`
+}
+
+// ListRules returns all active custom rules
+func (h *AdminHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	RespondJSON(w, h.logger, http.StatusOK, RulesResponse{
+		Rules: h.disposableService.Rules().List(),
+	})
+}
+
+// GetRule returns the custom rule for a domain
+func (h *AdminHandler) GetRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.disposableService.Rules().Get(r.PathValue("domain"))
+	if !ok {
+		RespondError(w, h.logger, http.StatusNotFound, "Rule not found")
+		return
+	}
+	RespondJSON(w, h.logger, http.StatusOK, rule)
+}
+
+// PutRule creates or replaces the custom rule for a domain
+func (h *AdminHandler) PutRule(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 64<<10) // 64KB
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	var req RuleRequest
+	if err := dec.Decode(&req); err != nil {
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	rule, err := h.disposableService.Rules().Put(service.Rule{
+		Domain:    r.PathValue("domain"),
+		Action:    req.Action,
+		Author:    req.Author,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if errors.Is(err, service.ErrInvalidRule) {
+		RespondError(w, h.logger, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, service.ErrRuleShadowed) {
+		RespondError(w, h.logger, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to store rule", slog.Any("error", err))
+		RespondError(w, h.logger, http.StatusInternalServerError, "Failed to store rule")
+		return
+	}
+
+	h.logger.Info("custom rule stored",
+		slog.String("domain", rule.Domain),
+		slog.String("action", rule.Action),
+		slog.String("author", rule.Author),
+		slog.String("reason", rule.Reason))
+	RespondJSON(w, h.logger, http.StatusOK, rule)
+}
+
+// DeleteRule removes the custom rule for a domain
+func (h *AdminHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	domainName := r.PathValue("domain")
+	deleted, err := h.disposableService.Rules().Delete(domainName)
+	if err != nil {
+		h.logger.Error("failed to delete rule", slog.Any("error", err))
+		RespondError(w, h.logger, http.StatusInternalServerError, "Failed to delete rule")
+		return
+	}
+	if !deleted {
+		RespondError(w, h.logger, http.StatusNotFound, "Rule not found")
+		return
+	}
+
+	h.logger.Info("custom rule deleted", slog.String("domain", domainName))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// explainVerdict describes in words which rule decided a verdict
+func explainVerdict(v domain.Verdict) string {
+	via := ""
+	if v.MatchedDomain != "" && v.MatchedDomain != v.Domain {
+		via = fmt.Sprintf(" via parent domain %s", v.MatchedDomain)
+	}
+
+	switch v.Source {
+	case domain.SourceCustomAllow:
+		return fmt.Sprintf("allowed by custom rule from %s%s", v.Origin, via)
+	case domain.SourceCustomDeny:
+		return fmt.Sprintf("blocked by custom rule from %s%s", v.Origin, via)
+	case domain.SourceAllowlist:
+		return fmt.Sprintf("allowed by allowlist entry from %s%s", v.Origin, via)
+	case domain.SourceDenylist:
+		return fmt.Sprintf("blocked by denylist entry from %s%s", v.Origin, via)
+	case domain.SourceList:
+		if v.MatchedRule != "" {
+			return fmt.Sprintf("matches pattern %s listed by %s%s", v.MatchedRule, v.Origin, via)
+		}
+		return fmt.Sprintf("listed by %s%s", v.Origin, via)
+	case domain.SourceMX:
+		if v.MatchedIP != "" {
+			return fmt.Sprintf("mail exchanger %s (%s) is in disposable range %s from %s", v.MXHost, v.MatchedIP, v.MatchedDomain, v.Origin)
+		}
+		return fmt.Sprintf("mail exchanger %s matches disposable mail exchanger %s from %s", v.MXHost, v.MatchedDomain, v.Origin)
+	case domain.SourceNS:
+		if v.MatchedIP != "" {
+			return fmt.Sprintf("nameserver %s (%s) is in disposable range %s from %s", v.NSHost, v.MatchedIP, v.MatchedDomain, v.Origin)
+		}
+		return fmt.Sprintf("nameserver %s matches disposable nameserver %s from %s", v.NSHost, v.MatchedDomain, v.Origin)
+	default:
+		return "not listed"
+	}
+}
+
+// queryInt parses an integer query parameter, returning def when absent
+func queryInt(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+// newAdminMux routes the admin endpoints as the server does
+func newAdminMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	rules, err := service.OpenRuleStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewAdminHandler(newTestService(t, service.Options{Rules: rules}), slog.New(slog.DiscardHandler))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/admin/refresh", h.Refresh)
+	mux.HandleFunc("GET /v1/admin/refresh/history", h.History)
+	mux.HandleFunc("GET /v1/admin/domains", h.ListDomains)
+	mux.HandleFunc("GET /v1/admin/domains/{domain}", h.LookupDomain)
+	mux.HandleFunc("GET /v1/admin/rules", h.ListRules)
+	mux.HandleFunc("GET /v1/admin/rules/{domain}", h.GetRule)
+	mux.HandleFunc("PUT /v1/admin/rules/{domain}", h.PutRule)
+	mux.HandleFunc("DELETE /v1/admin/rules/{domain}", h.DeleteRule)
+	return mux
+}
+
+// serve sends a request to mux and returns the recorded response
+func serve(mux http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestAdminRules(t *testing.T) {
+	mux := newAdminMux(t)
+
+	// Steps run in order against the same store
+	steps := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		wantStatus int
+	}{
+		{"missing rule", http.MethodGet, "/v1/admin/rules/a.example.com", "", http.StatusNotFound},
+		{"create deny", http.MethodPut, "/v1/admin/rules/A.Example.com", `{"action": "deny", "author": "ops", "reason": "abuse"}`, http.StatusOK},
+		{"get normalized", http.MethodGet, "/v1/admin/rules/a.example.com", "", http.StatusOK},
+		{"allow over denied subdomain", http.MethodPut, "/v1/admin/rules/example.com", `{"action": "allow", "author": "ops"}`, http.StatusConflict},
+		{"invalid action", http.MethodPut, "/v1/admin/rules/b.com", `{"action": "block", "author": "ops"}`, http.StatusBadRequest},
+		{"missing author", http.MethodPut, "/v1/admin/rules/b.com", `{"action": "deny"}`, http.StatusBadRequest},
+		{"unknown field", http.MethodPut, "/v1/admin/rules/b.com", `{"action": "deny", "author": "ops", "ttl": 5}`, http.StatusBadRequest},
+		{"delete", http.MethodDelete, "/v1/admin/rules/a.example.com", "", http.StatusNoContent},
+		{"delete again", http.MethodDelete, "/v1/admin/rules/a.example.com", "", http.StatusNotFound},
+		{"allow once the deny rule is gone", http.MethodPut, "/v1/admin/rules/example.com", `{"action": "allow", "author": "ops"}`, http.StatusOK},
+		{"deny under allowed parent", http.MethodPut, "/v1/admin/rules/mail.example.com", `{"action": "deny", "author": "ops"}`, http.StatusConflict},
+	}
+	for _, step := range steps {
+		rec := serve(mux, step.method, step.target, step.body)
+		if rec.Code != step.wantStatus {
+			t.Fatalf("%s: %s %s = %d, want %d: %s", step.name, step.method, step.target, rec.Code, step.wantStatus, rec.Body)
+		}
+	}
+
+	var resp RulesResponse
+	if err := json.Unmarshal(serve(mux, http.MethodGet, "/v1/admin/rules", "").Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Rules) != 1 || resp.Rules[0].Domain != "example.com" || resp.Rules[0].Action != service.RuleAllow {
+		t.Errorf("rules = %+v, want the example.com allow rule", resp.Rules)
+	}
+}
+
+func TestAdminDomains(t *testing.T) {
+	mux := newAdminMux(t)
+
+	tests := []struct {
+		name        string
+		target      string
+		wantStatus  int
+		wantDomains []string
+	}{
+		{"all", "/v1/admin/domains", http.StatusOK, []string{"mailinator.com", "tempmail.com"}},
+		{"page", "/v1/admin/domains?offset=1&limit=1", http.StatusOK, []string{"tempmail.com"}},
+		{"past the end", "/v1/admin/domains?offset=5", http.StatusOK, nil},
+		{"negative offset", "/v1/admin/domains?offset=-1", http.StatusBadRequest, nil},
+		{"limit too large", "/v1/admin/domains?limit=1001", http.StatusBadRequest, nil},
+		{"invalid limit", "/v1/admin/domains?limit=ten", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(mux, http.MethodGet, tt.target, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp DomainsResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			var domains []string
+			for _, entry := range resp.Domains {
+				domains = append(domains, entry.Domain)
+			}
+			if resp.Total != 2 || strings.Join(domains, ",") != strings.Join(tt.wantDomains, ",") {
+				t.Errorf("domains = %d %q, want 2 %q", resp.Total, domains, tt.wantDomains)
+			}
+		})
+	}
+}
+
+func TestAdminLookupDomain(t *testing.T) {
+	mux := newAdminMux(t)
+
+	tests := []struct {
+		domain         string
+		wantStatus     int
+		wantDisposable bool
+		wantMatched    string
+	}{
+		{"tempmail.com", http.StatusOK, true, "tempmail.com"},
+		{"mx.TempMail.com", http.StatusOK, true, "tempmail.com"},
+		{"example.com", http.StatusOK, false, ""},
+		{"not_a_domain", http.StatusBadRequest, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			rec := serve(mux, http.MethodGet, "/v1/admin/domains/"+tt.domain, "")
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp LookupResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Disposable != tt.wantDisposable || resp.MatchedDomain != tt.wantMatched || resp.Reason == "" {
+				t.Errorf("lookup = %+v, want disposable %v, matched %q", resp, tt.wantDisposable, tt.wantMatched)
+			}
+		})
+	}
+}
+
+func TestAdminRefresh(t *testing.T) {
+	mux := newAdminMux(t)
+
+	rec := serve(mux, http.MethodPost, "/v1/admin/refresh", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh status = %d: %s", rec.Code, rec.Body)
+	}
+	var report service.RefreshReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Success || report.Trigger != service.TriggerManual || report.DomainCount != 2 || len(report.Sources) != 1 {
+		t.Errorf("report = %+v", report)
+	}
+
+	var history HistoryResponse
+	if err := json.Unmarshal(serve(mux, http.MethodGet, "/v1/admin/refresh/history", "").Body.Bytes(), &history); err != nil {
+		t.Fatal(err)
+	}
+	// The refresh that loaded the test list, then the manual one, newest first
+	if len(history.Refreshes) != 2 || history.Refreshes[0].StartedAt.Before(history.Refreshes[1].StartedAt) {
+		t.Errorf("history = %+v, want two refreshes newest first", history.Refreshes)
+	}
+}
@@ -0,0 +1,286 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+const (
+	// batchDeadlineExtension is how far read/write deadlines are pushed out
+	// after every NDJSON item, so long inputs outlive the server timeouts
+	batchDeadlineExtension = 30 * time.Second
+	// batchFlushEvery is how many NDJSON results are written between flushes
+	batchFlushEvery = 500
+	// batchFlushInterval is the longest NDJSON results are held back before
+	// a flush, for slow inputs or checks
+	batchFlushInterval = time.Second
+	// maxBatchLine caps a single NDJSON input line
+	maxBatchLine = 64 << 10
+)
+
+// Batch verdicts, in addition to the service.Decide outcomes
+const batchVerdictUnavailable = "unavailable"
+
+// BatchHandler validates many email addresses per request, for audits and backfills
+type BatchHandler struct {
+	disposableService *service.DisposableEmailService
+	maxItems          int
+	logger            *slog.Logger
+}
+
+// NewBatchHandler creates a batch handler. maxItems caps JSON array inputs;
+// NDJSON inputs are streamed and not capped.
+func NewBatchHandler(svc *service.DisposableEmailService, maxItems int, log *slog.Logger) *BatchHandler {
+	return &BatchHandler{
+		disposableService: svc,
+		maxItems:          maxItems,
+		logger:            log,
+	}
+}
+
+// BatchResult is the verdict for one input item
+type BatchResult struct {
+	Index         int    `json:"index"`
+	Email         string `json:"email"`
+	Verdict       string `json:"verdict"`
+	Domain        string `json:"domain,omitempty"`
+	DomainUnicode string `json:"domain_unicode,omitempty"`
+	MatchedDomain string `json:"matched_domain,omitempty"`
+	MatchedRule   string `json:"matched_rule,omitempty"`
+	MXHost        string `json:"mx_host,omitempty"`
+	NSHost        string `json:"ns_host,omitempty"`
+	MatchedIP     string `json:"matched_ip,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Origin        string `json:"origin,omitempty"`
+	Reason        string `json:"reason"`
+	// FreeMail is the matching free mailbox provider, reported even when the
+	// request does not reject free mail
+	FreeMail string `json:"free_mail,omitempty"`
+	// RoleAccount, RandomLocalPart and LocalPartPattern are the local part
+	// findings, reported even when the request does not reject them
+	RoleAccount      string `json:"role_account,omitempty"`
+	RandomLocalPart  string `json:"random_local_part,omitempty"`
+	LocalPartPattern string `json:"local_part_pattern,omitempty"`
+	// Risk is the score breakdown, present when risk scoring is enabled
+	Risk *domain.Risk `json:"risk,omitempty"`
+}
+
+// BatchResponse is the response to a JSON array input
+type BatchResponse struct {
+	Results []BatchResult `json:"results"`
+}
+
+// Handle dispatches on the request content type:
+//   - application/json: ["a@x.com", ...] or {"emails": [...]}; responds with {"results": [...]}
+//   - application/x-ndjson: one email per line, either a JSON string, an
+//     {"email": "..."} object or plain text; responds with one result per line
+//
+// The "reject" query parameter selects the rejection categories as for the
+// webhook.
+func (h *BatchHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		RespondError(w, h.logger, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	reject, err := parseReject(r)
+	if err != nil {
+		RespondError(w, h.logger, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl", "application/jsonlines":
+		h.handleNDJSON(w, r, reject)
+	default:
+		h.handleJSON(w, r, reject)
+	}
+}
+
+// handleJSON validates a JSON array of emails
+func (h *BatchHandler) handleJSON(w http.ResponseWriter, r *http.Request, reject service.Rejections) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.maxItems)*1024)
+	var raw json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	var emails []string
+	if err := json.Unmarshal(raw, &emails); err != nil {
+		var wrapped struct {
+			Emails []string `json:"emails"`
+		}
+		if err := json.Unmarshal(raw, &wrapped); err != nil || wrapped.Emails == nil {
+			RespondError(w, h.logger, http.StatusBadRequest, "Expected an array of emails or {\"emails\": [...]}")
+			return
+		}
+		emails = wrapped.Emails
+	}
+	if len(emails) > h.maxItems {
+		RespondError(w, h.logger, http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Too many emails: %d (max %d, use NDJSON for larger inputs)", len(emails), h.maxItems))
+		return
+	}
+
+	results := make([]BatchResult, 0, len(emails))
+	for i, email := range emails {
+		results = append(results, h.check(r.Context(), i, email, reject))
+	}
+
+	h.logger.Info("batch validated", slog.Int("items", len(results)), slog.String("format", "json"))
+	RespondJSON(w, h.logger, http.StatusOK, BatchResponse{Results: results})
+}
+
+// handleNDJSON streams results while the request body is still being read
+func (h *BatchHandler) handleNDJSON(w http.ResponseWriter, r *http.Request, reject service.Rejections) {
+	rc := http.NewResponseController(w)
+	// Write results while the request is still being read
+	if err := rc.EnableFullDuplex(); err != nil {
+		h.logger.Debug("full duplex not supported", slog.Any("error", err))
+	}
+	h.extendDeadlines(rc)
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxBatchLine)
+
+	count := 0
+	lastFlush := time.Now()
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if err := enc.Encode(h.check(r.Context(), count, parseBatchLine(line), reject)); err != nil {
+			h.logger.Warn("batch client went away", slog.Int("items", count), slog.Any("error", err))
+			return
+		}
+		count++
+		h.extendDeadlines(rc)
+
+		if count%batchFlushEvery == 0 || time.Since(lastFlush) >= batchFlushInterval {
+			if err := rc.Flush(); err != nil {
+				h.logger.Warn("failed to flush batch results", slog.Any("error", err))
+				return
+			}
+			lastFlush = time.Now()
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		// Headers are already sent; report the failure as a final line
+		h.logger.Error("failed to read batch input", slog.Int("items", count), slog.Any("error", err))
+		_ = enc.Encode(map[string]string{"error": "failed to read input: " + err.Error()})
+		return
+	}
+
+	h.logger.Info("batch validated", slog.Int("items", count), slog.String("format", "ndjson"))
+}
+
+// parseBatchLine extracts the email from an NDJSON line
+func parseBatchLine(line string) string {
+	switch line[0] {
+	case '"':
+		var email string
+		if json.Unmarshal([]byte(line), &email) == nil {
+			return email
+		}
+	case '{':
+		var item struct {
+			Email string `json:"email"`
+		}
+		if json.Unmarshal([]byte(line), &item) == nil {
+			return item.Email
+		}
+	}
+	return line
+}
+
+// check runs the same logic as the Kratos webhook for a single item
+func (h *BatchHandler) check(ctx context.Context, index int, email string, reject service.Rejections) BatchResult {
+	result := BatchResult{Index: index, Email: email}
+
+	verdict, err := h.disposableService.CheckContext(service.WithAudit(ctx), email)
+	switch {
+	case errors.Is(err, domain.ErrListUnavailable):
+		result.Verdict = batchVerdictUnavailable
+		result.Domain = verdict.Domain
+		result.DomainUnicode = verdict.DomainUnicode
+		result.Reason = "disposable list unavailable"
+		metrics.BatchValidations.WithLabelValues(metrics.VerdictFailClosed).Inc()
+		return result
+	case err != nil:
+		result.Verdict = metrics.VerdictInvalid
+		result.Reason = err.Error()
+		metrics.BatchValidations.WithLabelValues(metrics.VerdictInvalid).Inc()
+		return result
+	}
+
+	result.Domain = verdict.Domain
+	result.DomainUnicode = verdict.DomainUnicode
+	result.MatchedDomain = verdict.MatchedDomain
+	result.MatchedRule = verdict.MatchedRule
+	result.MXHost = verdict.MXHost
+	result.NSHost = verdict.NSHost
+	result.MatchedIP = verdict.MatchedIP
+	result.Source = verdict.Source
+	result.Origin = verdict.Origin
+	result.Reason = explainVerdict(verdict)
+	result.Risk = verdict.Risk
+	result.FreeMail = verdict.FreeMail
+	result.RoleAccount = verdict.RoleAccount
+	result.RandomLocalPart = verdict.RandomLocalPart
+	result.LocalPartPattern = verdict.LocalPartPattern
+
+	decision := service.Decide(verdict, reject)
+	result.Verdict = decision.Outcome
+	switch decision.Outcome {
+	case service.RejectFreeMail:
+		result.Reason = fmt.Sprintf("free mailbox provider %s listed by %s", verdict.FreeMail, verdict.FreeMailOrigin)
+	case service.RejectRoleAccount:
+		result.Reason = "role account " + verdict.RoleAccount
+	case service.RejectLocalPartPattern:
+		result.Reason = "local part matches pattern " + verdict.LocalPartPattern
+	case service.RejectRandomLocalPart:
+		result.Reason = "randomly generated local part: " + verdict.RandomLocalPart
+	case service.DecisionFlagged:
+		result.Reason = fmt.Sprintf("risk score %d reached the flag threshold", verdict.Risk.Score)
+	case service.DecisionRisky:
+		result.Reason = fmt.Sprintf("risk score %d reached the block threshold", verdict.Risk.Score)
+	case service.DecisionUndeliverable:
+		result.Reason = "domain cannot receive email: " + verdict.Undeliverable
+	case service.DecisionFailOpen:
+		result.Reason = "disposable list not loaded yet (fail open)"
+	}
+	metrics.BatchValidations.WithLabelValues(verdictLabel(decision.Outcome)).Inc()
+
+	return result
+}
+
+// extendDeadlines pushes the connection deadlines out while streaming
+func (h *BatchHandler) extendDeadlines(rc *http.ResponseController) {
+	deadline := time.Now().Add(batchDeadlineExtension)
+	if err := rc.SetReadDeadline(deadline); err != nil {
+		h.logger.Debug("failed to extend read deadline", slog.Any("error", err))
+	}
+	if err := rc.SetWriteDeadline(deadline); err != nil {
+		h.logger.Debug("failed to extend write deadline", slog.Any("error", err))
+	}
+}
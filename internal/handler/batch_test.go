@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+// testList is the disposable list loaded by newTestService
+const testList = "tempmail.com\nmailinator.com\n"
+
+// newTestService creates a service with opts that has loaded testList
+func newTestService(t *testing.T, opts service.Options) *service.DisposableEmailService {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "list.txt")
+	if err := os.WriteFile(path, []byte(testList), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts.ListURLs = []string{path}
+	s := service.NewDisposableEmailService(opts, slog.New(slog.DiscardHandler))
+	if _, err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestBatchJSON(t *testing.T) {
+	h := NewBatchHandler(newTestService(t, service.Options{}), 3, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		name         string
+		target       string
+		body         string
+		wantStatus   int
+		wantVerdicts []string
+	}{
+		{"array", "/", `["a@tempmail.com", "b@example.com", "not an email"]`, http.StatusOK, []string{"disposable", "allowed", "invalid"}},
+		{"wrapped", "/", `{"emails": ["a@sub.mailinator.com"]}`, http.StatusOK, []string{"disposable"}},
+		{"reject role accounts", "/?reject=role_account", `["admin@example.com"]`, http.StatusOK, []string{"role_account"}},
+		{"free mail allowed", "/", `["a@gmail.com"]`, http.StatusOK, []string{"allowed"}},
+		{"reject free mail", "/?reject=free_mail", `["a@gmail.com", "b@tempmail.com"]`, http.StatusOK, []string{"free_mail", "disposable"}},
+		{"unknown reject category", "/?reject=spam", `[]`, http.StatusBadRequest, nil},
+		{"too many", "/", `["a@x.com", "b@x.com", "c@x.com", "d@x.com"]`, http.StatusRequestEntityTooLarge, nil},
+		{"malformed", "/", `{"emails": "a@x.com"}`, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			h.Handle(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var resp BatchResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Results) != len(tt.wantVerdicts) {
+				t.Fatalf("got %d results, want %d", len(resp.Results), len(tt.wantVerdicts))
+			}
+			for i, result := range resp.Results {
+				if result.Index != i || result.Verdict != tt.wantVerdicts[i] {
+					t.Errorf("result %d = %d/%s, want %d/%s", i, result.Index, result.Verdict, i, tt.wantVerdicts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBatchNDJSON(t *testing.T) {
+	h := NewBatchHandler(newTestService(t, service.Options{}), 1, slog.New(slog.DiscardHandler))
+	srv := httptest.NewServer(http.HandlerFunc(h.Handle))
+	t.Cleanup(srv.Close)
+
+	// NDJSON inputs are not capped by maxItems
+	body := strings.Join([]string{
+		`"a@tempmail.com"`,
+		``,
+		`{"email": "b@example.com"}`,
+		`c@mailinator.com`,
+		`not an email`,
+	}, "\n")
+	resp, err := http.Post(srv.URL, "application/x-ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	tests := []struct {
+		email   string
+		verdict string
+	}{
+		{"a@tempmail.com", "disposable"},
+		{"b@example.com", "allowed"},
+		{"c@mailinator.com", "disposable"},
+		{"not an email", "invalid"},
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	i := 0
+	for ; scanner.Scan(); i++ {
+		var result BatchResult
+		if err := json.Unmarshal(scanner.Bytes(), &result); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if i >= len(tests) {
+			t.Fatalf("unexpected result %+v", result)
+		}
+		if result.Index != i || result.Email != tests[i].email || result.Verdict != tests[i].verdict {
+			t.Errorf("result %d = %d/%s/%s, want %d/%s/%s",
+				i, result.Index, result.Email, result.Verdict, i, tests[i].email, tests[i].verdict)
+		}
+	}
+	if i != len(tests) {
+		t.Errorf("got %d results, want %d", i, len(tests))
+	}
+}
+
+func TestParseBatchLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{`"a@x.com"`, "a@x.com"},
+		{`{"email": "a@x.com", "id": 7}`, "a@x.com"},
+		{`a@x.com`, "a@x.com"},
+		{`"unterminated`, `"unterminated`},
+		{`{not json}`, `{not json}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := parseBatchLine(tt.line); got != tt.want {
+				t.Errorf("parseBatchLine(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,42 +2,172 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
 )
 
+// Health statuses (application/health+json)
+const (
+	healthPass = "pass"
+	healthWarn = "warn"
+	healthFail = "fail"
+)
+
 type HealthHandler struct {
 	disposableService *service.DisposableEmailService
+	maxStaleness      time.Duration
 	logger            *slog.Logger
 }
 
-func NewHealthHandler(svc *service.DisposableEmailService, log *slog.Logger) *HealthHandler {
+// NewHealthHandler creates a health handler. When maxStaleness is positive,
+// readiness fails once the list is older than maxStaleness.
+func NewHealthHandler(svc *service.DisposableEmailService, maxStaleness time.Duration, log *slog.Logger) *HealthHandler {
 	return &HealthHandler{
 		disposableService: svc,
+		maxStaleness:      maxStaleness,
 		logger:            log,
 	}
 }
 
-// HealthResponse uses a minimal format (application/health+json)
-// with status values: "healthy" or "unhealthy".
+// HealthResponse uses the application/health+json format
+// with status values: "pass", "warn" or "fail".
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status string                   `json:"status"`
+	Output string                   `json:"output,omitempty"`
+	Checks map[string][]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is a single entry of the "checks" section
+type HealthCheck struct {
+	ComponentID   string      `json:"componentId,omitempty"`
+	ComponentType string      `json:"componentType,omitempty"`
+	ObservedValue interface{} `json:"observedValue,omitempty"`
+	ObservedUnit  string      `json:"observedUnit,omitempty"`
+	Status        string      `json:"status"`
+	Time          *time.Time  `json:"time,omitempty"`
+	Output        string      `json:"output,omitempty"`
+
+	// Source details (list-source checks only)
+	ETag        string     `json:"etag,omitempty"`
+	DomainCount *int       `json:"domainCount,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure *time.Time `json:"lastFailure,omitempty"`
+	Active      *bool      `json:"active,omitempty"`
 }
 
+// Handle reports readiness; kept at /health for backward compatibility
 func (h *HealthHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	isReady := h.disposableService.IsReady()
+	h.Ready(w, r)
+}
+
+// Live reports whether the process is up. It never depends on the list, so
+// an unreachable CDN does not get the pod restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	h.respond(w, http.StatusOK, HealthResponse{Status: healthPass})
+}
 
-	status := "fail"
-	code := http.StatusServiceUnavailable
-	if isReady {
-		status = "pass"
-		code = http.StatusOK
+// Ready reports whether a list is loaded and, if configured, fresh enough
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	status := h.disposableService.Status()
+
+	code := http.StatusOK
+	resp := HealthResponse{Status: healthPass}
+	if reason := h.notReadyReason(status); reason != "" {
+		code = http.StatusServiceUnavailable
+		resp = HealthResponse{Status: healthFail, Output: reason}
 	}
 
-	resp := HealthResponse{Status: status}
+	h.respond(w, code, resp)
+}
 
+// Details reports readiness with a "checks" section describing the list and
+// every source. It exposes source URLs, so it must sit behind authentication.
+func (h *HealthHandler) Details(w http.ResponseWriter, r *http.Request) {
+	status := h.disposableService.Status()
+	age := h.disposableService.ListAge()
+
+	resp := HealthResponse{
+		Status: healthPass,
+		Checks: make(map[string][]HealthCheck),
+	}
+	code := http.StatusOK
+	if reason := h.notReadyReason(status); reason != "" {
+		code = http.StatusServiceUnavailable
+		resp.Status = healthFail
+		resp.Output = reason
+	}
+
+	listCheck := HealthCheck{
+		ComponentID:   "disposable-list",
+		ComponentType: "datastore",
+		ObservedValue: status.DomainCount,
+		ObservedUnit:  "domains",
+		Status:        healthPass,
+	}
+	if !status.LastRefresh.IsZero() {
+		listCheck.Time = &status.LastRefresh
+	}
+	if status.Degraded {
+		listCheck.Status = healthFail
+		listCheck.Output = "degraded mode: no list has loaded yet"
+	}
+	resp.Checks["disposable-list:domains"] = []HealthCheck{listCheck}
+
+	ageCheck := HealthCheck{
+		ComponentID:   "disposable-list",
+		ComponentType: "datastore",
+		ObservedValue: int64(age.Seconds()),
+		ObservedUnit:  "s",
+		Status:        healthPass,
+	}
+	if h.maxStaleness > 0 && age > h.maxStaleness {
+		ageCheck.Status = healthFail
+		ageCheck.Output = fmt.Sprintf("list older than max staleness %s", h.maxStaleness)
+	}
+	resp.Checks["disposable-list:age"] = []HealthCheck{ageCheck}
+
+	sources := make([]HealthCheck, 0, len(status.Sources)+len(status.HostSources))
+	for _, src := range status.Sources {
+		sources = append(sources, sourceCheck(src))
+	}
+	resp.Checks["list-source:fetch"] = sources[:len(status.Sources)]
+	if len(status.HostSources) > 0 {
+		for _, src := range status.HostSources {
+			sources = append(sources, sourceCheck(src))
+		}
+		resp.Checks["host-list-source:fetch"] = sources[len(status.Sources):]
+	}
+
+	if resp.Status == healthPass {
+		for _, check := range sources {
+			if check.Status != healthPass {
+				resp.Status = healthWarn
+				break
+			}
+		}
+	}
+
+	h.respond(w, code, resp)
+}
+
+// notReadyReason explains why the service is not ready, or returns ""
+func (h *HealthHandler) notReadyReason(status service.Status) string {
+	if !status.Ready {
+		return "disposable list not loaded"
+	}
+	if h.maxStaleness > 0 {
+		if age := h.disposableService.ListAge(); age > h.maxStaleness {
+			return fmt.Sprintf("disposable list is %s old (max %s)", age.Round(time.Second), h.maxStaleness)
+		}
+	}
+	return ""
+}
+
+func (h *HealthHandler) respond(w http.ResponseWriter, code int, resp HealthResponse) {
 	w.Header().Set("Content-Type", "application/health+json")
 	w.WriteHeader(code)
 
@@ -45,3 +175,32 @@ func (h *HealthHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		h.logger.Error("failed to encode health response", slog.Any("error", err))
 	}
 }
+
+// sourceCheck reports the fetch state of a single list source
+func sourceCheck(src service.SourceStatus) HealthCheck {
+	check := HealthCheck{
+		ComponentID:   src.URL,
+		ComponentType: "component",
+		Status:        healthPass,
+		Output:        src.LastError,
+		ETag:          src.ETag,
+		DomainCount:   &src.DomainCount,
+		Active:        &src.Active,
+	}
+	if !src.LastSuccess.IsZero() {
+		check.LastSuccess = &src.LastSuccess
+		check.Time = &src.LastSuccess
+	}
+	if !src.LastFailure.IsZero() {
+		check.LastFailure = &src.LastFailure
+		if src.LastFailure.After(src.LastSuccess) {
+			// Last attempt failed: warn while a previous copy is still
+			// served, fail when the source never loaded
+			check.Status = healthWarn
+			if src.LastSuccess.IsZero() {
+				check.Status = healthFail
+			}
+		}
+	}
+	return check
+}
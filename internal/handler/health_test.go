@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+	loaded := NewHealthHandler(newTestService(t, service.Options{}), 0, log)
+	empty := NewHealthHandler(service.NewDisposableEmailService(service.Options{ListURLs: []string{"/nonexistent"}}, log), 0, log)
+
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantHealth string
+	}{
+		{"live", loaded.Live, http.StatusOK, healthPass},
+		{"live without a list", empty.Live, http.StatusOK, healthPass},
+		{"ready", loaded.Ready, http.StatusOK, healthPass},
+		{"ready without a list", empty.Ready, http.StatusServiceUnavailable, healthFail},
+		{"legacy alias", empty.Handle, http.StatusServiceUnavailable, healthFail},
+		{"details", loaded.Details, http.StatusOK, healthPass},
+		{"details without a list", empty.Details, http.StatusServiceUnavailable, healthFail},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			tt.handler(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/health+json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			var resp HealthResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.wantHealth {
+				t.Errorf("health status = %q, want %q", resp.Status, tt.wantHealth)
+			}
+		})
+	}
+}
+
+func TestHealthDetailsChecks(t *testing.T) {
+	h := NewHealthHandler(newTestService(t, service.Options{}), 0, slog.New(slog.DiscardHandler))
+	rec := httptest.NewRecorder()
+	h.Details(rec, httptest.NewRequest(http.MethodGet, "/health/details", nil))
+
+	var resp HealthResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	domains := resp.Checks["disposable-list:domains"]
+	if len(domains) != 1 || domains[0].ObservedValue != float64(2) {
+		t.Errorf("disposable-list:domains = %+v, want 2 domains", domains)
+	}
+	sources := resp.Checks["list-source:fetch"]
+	if len(sources) != 1 {
+		t.Fatalf("list-source:fetch = %+v, want one source", sources)
+	}
+	if src := sources[0]; src.Status != healthPass || *src.DomainCount != 2 || !*src.Active || src.LastSuccess == nil {
+		t.Errorf("source check = %+v", src)
+	}
+	if _, ok := resp.Checks["host-list-source:fetch"]; ok {
+		t.Error("host-list-source:fetch reported without host lists")
+	}
+}
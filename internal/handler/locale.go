@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// preferredLocales returns the locales requested for a webhook call, most
+// preferred first: the locale at the first resolving locale pointer (e.g. a
+// custom {"locale": "de"} body or an identity trait), then the
+// Accept-Language header Kratos forwards in "request_headers", then the
+// Accept-Language header of the webhook request itself
+func preferredLocales(payload interface{}, localePointers []string, r *http.Request) []language.Tag {
+	var tags []language.Tag
+	for _, pointer := range localePointers {
+		value, ok := resolvePointer(payload, pointer)
+		if !ok {
+			continue
+		}
+		if s, ok := value.(string); ok && s != "" {
+			if tag, err := language.Parse(s); err == nil {
+				tags = append(tags, tag)
+				break
+			}
+		}
+	}
+
+	for _, header := range []string{forwardedHeader(payload, "Accept-Language"), r.Header.Get("Accept-Language")} {
+		if header == "" {
+			continue
+		}
+		if accepted, _, err := language.ParseAcceptLanguage(header); err == nil {
+			tags = append(tags, accepted...)
+		}
+	}
+	return tags
+}
+
+// forwardedHeader returns a header from the "request_headers" object of the
+// Kratos web_hook context, whose values are arrays of strings
+func forwardedHeader(payload interface{}, name string) string {
+	doc, ok := payload.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	headers, ok := doc["request_headers"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	for key, value := range headers {
+		if !strings.EqualFold(key, name) {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case []interface{}:
+			var values []string
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+			return strings.Join(values, ",")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+)
+
+// resolvePointer evaluates an RFC 6901 JSON pointer against a decoded document
+func resolvePointer(doc interface{}, pointer string) (interface{}, bool) {
+	if pointer == "" {
+		return doc, true
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, false
+	}
+
+	current := doc
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// instancePtr converts a payload pointer into the Kratos instance_ptr of the
+// identity field it refers to. Pointers into the Kratos context
+// ("/identity/traits/email") map to "#/traits/email"; pointers into a custom
+// body ("/email") are assumed to mirror a trait of the same name.
+func instancePtr(pointer string) string {
+	if rest, ok := strings.CutPrefix(pointer, "/identity/"); ok {
+		return "#/" + rest
+	}
+	return "#/traits" + pointer
+}
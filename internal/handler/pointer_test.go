@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResolvePointer(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"identity": {"traits": {"email": "a@x.com", "emails": ["b@x.com", "c@x.com"], "a/b": "slash", "m~n": "tilde"}},
+		"flow": {"type": "browser"}
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pointer string
+		want    interface{}
+		wantOK  bool
+	}{
+		{"/identity/traits/email", "a@x.com", true},
+		{"/identity/traits/emails/1", "c@x.com", true},
+		{"/identity/traits/a~1b", "slash", true},
+		{"/identity/traits/m~0n", "tilde", true},
+		{"/flow/type", "browser", true},
+		{"/identity/traits/phone", nil, false},
+		{"/identity/traits/emails/2", nil, false},
+		{"/identity/traits/emails/-1", nil, false},
+		{"/identity/traits/email/0", nil, false},
+		{"identity/traits/email", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			got, ok := resolvePointer(doc, tt.pointer)
+			if ok != tt.wantOK || (ok && got != tt.want) {
+				t.Errorf("resolvePointer(%q) = %v, %v; want %v, %v", tt.pointer, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if got, ok := resolvePointer(doc, ""); !ok || got == nil {
+		t.Error(`resolvePointer("") did not return the whole document`)
+	}
+}
+
+func TestInstancePtr(t *testing.T) {
+	tests := []struct {
+		pointer string
+		want    string
+	}{
+		{"/identity/traits/email", "#/traits/email"},
+		{"/identity/traits/emails/0", "#/traits/emails/0"},
+		{"/email", "#/traits/email"},
+		{"/recovery_email", "#/traits/recovery_email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pointer, func(t *testing.T) {
+			if got := instancePtr(tt.pointer); got != tt.want {
+				t.Errorf("instancePtr(%q) = %q, want %q", tt.pointer, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+// parseReject reads the "reject" query parameter, which may be repeated or
+// comma-separated, e.g. "/v1/validate/email?reject=disposable,free_mail"
+func parseReject(r *http.Request) (service.Rejections, error) {
+	return service.ParseRejections(r.URL.Query()["reject"])
+}
+
+// rejectionMessageGroup creates the message group for a rejecting decision
+func rejectionMessageGroup(instancePtr string, v domain.Verdict, d service.Decision) domain.MessageGroup {
+	switch {
+	case d.Category == service.RejectFreeMail:
+		return domain.NewFreeMailMessageGroup(instancePtr, v)
+	case d.Category != "":
+		return domain.NewLocalPartMessageGroup(instancePtr, v, d.Category)
+	case v.Risk != nil:
+		return domain.NewRiskMessageGroup(instancePtr, v)
+	case v.Disposable:
+		return domain.NewDisposableMessageGroup(instancePtr, v)
+	}
+	return domain.NewUndeliverableMessageGroup(instancePtr, v)
+}
+
+// outcomeVerdicts maps decision outcomes to metrics verdict labels
+var outcomeVerdicts = map[string]string{
+	service.DecisionAllowed:        metrics.VerdictAllowed,
+	service.DecisionFailOpen:       metrics.VerdictFailOpen,
+	service.DecisionDisposable:     metrics.VerdictDisposable,
+	service.DecisionUndeliverable:  metrics.VerdictUndeliverable,
+	service.DecisionFlagged:        metrics.VerdictFlagged,
+	service.DecisionRisky:          metrics.VerdictRisky,
+	service.RejectFreeMail:         metrics.VerdictFreeMail,
+	service.RejectRoleAccount:      metrics.VerdictRoleAccount,
+	service.RejectRandomLocalPart:  metrics.VerdictRandomLocalPart,
+	service.RejectLocalPartPattern: metrics.VerdictLocalPartPattern,
+}
+
+// verdictLabel returns the metrics verdict label for a decision outcome
+func verdictLabel(outcome string) string {
+	if label, ok := outcomeVerdicts[outcome]; ok {
+		return label
+	}
+	return outcome
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+func TestVerdictLabel(t *testing.T) {
+	tests := []struct {
+		outcome string
+		want    string
+	}{
+		{service.DecisionAllowed, metrics.VerdictAllowed},
+		{service.DecisionFailOpen, metrics.VerdictFailOpen},
+		{service.DecisionDisposable, metrics.VerdictDisposable},
+		{service.DecisionUndeliverable, metrics.VerdictUndeliverable},
+		{service.DecisionFlagged, metrics.VerdictFlagged},
+		{service.DecisionRisky, metrics.VerdictRisky},
+		{service.RejectFreeMail, metrics.VerdictFreeMail},
+		{service.RejectRoleAccount, metrics.VerdictRoleAccount},
+		{service.RejectRandomLocalPart, metrics.VerdictRandomLocalPart},
+		{service.RejectLocalPartPattern, metrics.VerdictLocalPartPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.outcome, func(t *testing.T) {
+			if got := verdictLabel(tt.outcome); got != tt.want {
+				t.Errorf("verdictLabel(%q) = %q, want %q", tt.outcome, got, tt.want)
+			}
+		})
+	}
+}
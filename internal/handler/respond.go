@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// RespondError sends an error about the request itself in the Ory webhook
+// message format, with ID domain.RequestErrorID(statusCode)
+func RespondError(w http.ResponseWriter, log *slog.Logger, statusCode int, message string) {
+	RespondJSON(w, log, statusCode, domain.NewRequestErrorResponse(statusCode, message))
+}
+
+// RespondJSON sends a JSON response
+func RespondJSON(w http.ResponseWriter, log *slog.Logger, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		log.Error("failed to encode response", slog.Any("error", err))
+	}
+}
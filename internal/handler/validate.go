@@ -2,23 +2,40 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/i18n"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
 )
 
 // ValidateHandler handles email validation requests from Ory Kratos
 type ValidateHandler struct {
 	disposableService *service.DisposableEmailService
+	emailPointers     []string
+	localePointers    []string
+	catalog           *i18n.Catalog
+	scheme            domain.MessageScheme
 	logger            *slog.Logger
 }
 
-// NewValidateHandler creates a new validation handler
-func NewValidateHandler(svc *service.DisposableEmailService, log *slog.Logger) *ValidateHandler {
+// NewValidateHandler creates a new validation handler.
+// emailPointers are JSON pointers tried in order to locate the email in the
+// request body, e.g. "/email" or "/identity/traits/email". Rejection
+// messages are translated with catalog (nil keeps the English text) into the
+// locale found at localePointers or in the Accept-Language headers, then
+// given the ID and type that scheme assigns to their outcome.
+func NewValidateHandler(svc *service.DisposableEmailService, emailPointers, localePointers []string, catalog *i18n.Catalog, scheme domain.MessageScheme, log *slog.Logger) *ValidateHandler {
 	return &ValidateHandler{
 		disposableService: svc,
+		emailPointers:     emailPointers,
+		localePointers:    localePointers,
+		catalog:           catalog,
+		scheme:            scheme,
 		logger:            log,
 	}
 }
@@ -30,85 +47,217 @@ func (h *ValidateHandler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	// Only accept POST requests
 	if r.Method != http.MethodPost {
-		h.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		RespondError(w, h.logger, http.StatusMethodNotAllowed, "Method not allowed")
 		return
 	}
 
-	// Parse the request body (simplified payload: {"email":"..."})
-	type ValidateRequest struct {
-		Email string `json:"email"`
+	// Each Kratos hook selects what to reject besides disposable addresses
+	reject, err := parseReject(r)
+	if err != nil {
+		RespondError(w, h.logger, http.StatusBadRequest, err.Error())
+		return
 	}
+
+	// Parse the request body: either a custom payload such as {"email":"..."}
+	// or the default Kratos web_hook context ({"identity": {...}, "flow": {...}})
 	// Limit body size to prevent abuse
 	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1MB
 	dec := json.NewDecoder(r.Body)
-	dec.DisallowUnknownFields()
+	dec.UseNumber()
 
-	var req ValidateRequest
-	if err := dec.Decode(&req); err != nil {
+	var payload interface{}
+	if err := dec.Decode(&payload); err != nil {
 		log.Error("failed to decode request", slog.Any("error", err))
-		h.respondError(w, http.StatusBadRequest, "Invalid request body")
+		RespondError(w, h.logger, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	if req.Email == "" {
-		h.respondError(w, http.StatusBadRequest, "Email is required")
+
+	fields := h.findEmails(payload)
+	if len(fields) == 0 {
+		RespondError(w, h.logger, http.StatusBadRequest, "Email is required")
+		return
+	}
+
+	// Check every address and collect one message group per offending field
+	var groups []domain.MessageGroup
+	for _, field := range fields {
+		group, rejected := h.checkField(w, r, field, reject)
+		if rejected {
+			groups = append(groups, group)
+		}
+	}
+
+	if len(groups) > 0 {
+		// Translate by the default IDs before the scheme remaps them
+		if h.catalog != nil {
+			locale := h.catalog.Match(preferredLocales(payload, h.localePointers, r)...)
+			h.catalog.Translate(groups, locale)
+			w.Header().Set("Content-Language", locale.String())
+		}
+		RespondJSON(w, h.logger, h.status(groups), domain.OryWebhookResponse{Messages: groups})
 		return
 	}
 
+	// Return 200 OK with empty response
+	RespondJSON(w, h.logger, http.StatusOK, struct{}{})
+}
+
+// status applies the message scheme to groups and picks the response status:
+// 200 when every message is informational, 503 when the list being
+// unavailable is the sole reason to reject, 400 otherwise
+func (h *ValidateHandler) status(groups []domain.MessageGroup) int {
+	unavailable := make([]bool, len(groups))
+	for i, group := range groups {
+		unavailable[i] = group.Messages[0].ID == domain.MessageIDUnavailable
+	}
+	h.scheme.Apply(groups)
+
+	blocking, blockingUnavailable := 0, 0
+	for i := range groups {
+		if !domain.Blocking(groups[i : i+1]) {
+			continue
+		}
+		blocking++
+		if unavailable[i] {
+			blockingUnavailable++
+		}
+	}
+	switch {
+	case blocking == 0:
+		return http.StatusOK
+	case blockingUnavailable == blocking:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// emailField is an email address found in the request body
+type emailField struct {
+	email       string
+	instancePtr string
+}
+
+// checkField validates a single email field against the selected rejection
+// categories. It returns the message group to report and whether the field
+// was rejected.
+func (h *ValidateHandler) checkField(w http.ResponseWriter, r *http.Request, field emailField, reject service.Rejections) (domain.MessageGroup, bool) {
+	log := h.logger
+	email := field.email
+
 	// Check if the email is disposable
-	isDisposable, emailDomain, err := h.disposableService.IsDisposable(req.Email)
+	verdict, err := h.disposableService.CheckContext(r.Context(), email)
+	if errors.Is(err, domain.ErrListUnavailable) {
+		// No list loaded and the failure policy rejects the request
+		log.Warn("rejecting email - disposable list unavailable",
+			slog.String("email", email))
+		metrics.Validations.WithLabelValues(metrics.VerdictFailClosed).Inc()
+		return domain.NewUnavailableMessageGroup(field.instancePtr, email), true
+	}
+	if errors.Is(err, domain.ErrInvalidEmail) {
+		log.Info("rejecting malformed email",
+			slog.String("email", email),
+			slog.String("instance_ptr", field.instancePtr),
+			slog.Any("error", err))
+		metrics.Validations.WithLabelValues(metrics.VerdictInvalid).Inc()
+		return domain.NewInvalidEmailMessageGroup(field.instancePtr, email, err), true
+	}
 	if err != nil {
 		log.Error("failed to check email",
 			slog.Any("error", err),
-			slog.String("email", req.Email))
-		h.respondError(w, http.StatusBadRequest, "Invalid email format")
-		return
+			slog.String("email", email))
+		metrics.Validations.WithLabelValues(metrics.VerdictInvalid).Inc()
+		return domain.NewInvalidEmailMessageGroup(field.instancePtr, email, err), true
 	}
 
-	// If disposable, return error response to interrupt the flow
-	if isDisposable {
-		log.Info("disposable email detected",
-			slog.String("email", req.Email),
-			slog.String("domain", emailDomain),
-		)
-
-		errorResp := domain.NewErrorResponse(req.Email, emailDomain)
-		h.respondJSON(w, http.StatusBadRequest, errorResp)
-		return
+	// Report which source decided the verdict (empty when nothing matched)
+	if verdict.Source != "" {
+		w.Header().Add("X-Verdict-Source", verdict.Source)
 	}
 
-	// Email is valid - allow flow to continue
-	log.Info("email validated successfully",
-		slog.String("email", req.Email))
+	decision := service.Decide(verdict, reject)
+	if verdict.Risk != nil {
+		w.Header().Add("X-Risk-Score", strconv.Itoa(verdict.Risk.Score))
+		w.Header().Add("X-Risk-Decision", verdict.Risk.Decision)
+	}
+	h.logDecision(field, verdict, decision)
+	metrics.Validations.WithLabelValues(verdictLabel(decision.Outcome)).Inc()
 
-	// Return 200 OK with empty response
-	h.respondJSON(w, http.StatusOK, struct{}{})
+	if !decision.Reject {
+		return domain.MessageGroup{}, false
+	}
+	return rejectionMessageGroup(field.instancePtr, verdict, decision), true
 }
 
-// respondError sends an error response
-func (h *ValidateHandler) respondError(w http.ResponseWriter, statusCode int, message string) {
-	resp := domain.OryWebhookResponse{
-		Messages: []domain.MessageGroup{
-			{
-				InstancePtr: "#/",
-				Messages: []domain.Message{
-					{
-						ID:   statusCode,
-						Text: message,
-						Type: "error",
-					},
-				},
-			},
-		},
-	}
-	h.respondJSON(w, statusCode, resp)
+// logDecision logs the decision for an email field
+func (h *ValidateHandler) logDecision(field emailField, verdict domain.Verdict, decision service.Decision) {
+	attrs := []any{
+		slog.String("email", field.email),
+		slog.String("instance_ptr", field.instancePtr),
+		slog.String("domain", verdict.Domain),
+	}
+
+	switch {
+	case decision.Category != "":
+		// Free mailbox providers and local part findings are rejected on
+		// hooks that ask for them
+		h.logger.Info("rejecting email - selected category", append(attrs,
+			slog.String("category", decision.Category),
+			slog.String("detail", decision.Detail))...)
+	case verdict.Risk != nil:
+		// With risk scoring only the decision counts
+		attrs = append(attrs,
+			slog.Int("score", verdict.Risk.Score),
+			slog.String("decision", verdict.Risk.Decision),
+			slog.Any("signals", verdict.Risk.SignalNames()),
+			slog.Bool("policy", verdict.Risk.Policy))
+		switch verdict.Risk.Decision {
+		case domain.RiskBlock:
+			h.logger.Info("rejecting email - risk score reached the block threshold", attrs...)
+		case domain.RiskFlag:
+			h.logger.Warn("flagged email allowed below the block threshold", attrs...)
+		default:
+			h.logger.Info("email validated successfully", attrs...)
+		}
+	case decision.Outcome == service.DecisionDisposable:
+		h.logger.Info("disposable email detected", append(attrs,
+			slog.String("matched_domain", verdict.MatchedDomain),
+			slog.String("source", verdict.Source),
+			slog.String("origin", verdict.Origin))...)
+	case decision.Outcome == service.DecisionUndeliverable:
+		// The DNS checks found that the domain cannot receive email
+		h.logger.Info("undeliverable email domain", append(attrs,
+			slog.String("reason", verdict.Undeliverable))...)
+	default:
+		h.logger.Info("email validated successfully", append(attrs,
+			slog.String("source", verdict.Source),
+			slog.String("origin", verdict.Origin))...)
+	}
 }
 
-// respondJSON sends a JSON response
-func (h *ValidateHandler) respondJSON(w http.ResponseWriter, statusCode int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
+// findEmails collects every email found at the configured pointers. A pointer
+// may resolve to a string or to an array of strings (e.g. "/identity/traits/emails").
+func (h *ValidateHandler) findEmails(payload interface{}) []emailField {
+	var fields []emailField
+	for _, pointer := range h.emailPointers {
+		value, ok := resolvePointer(payload, pointer)
+		if !ok {
+			continue
+		}
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
-		h.logger.Error("failed to encode response", slog.Any("error", err))
+		switch v := value.(type) {
+		case string:
+			if v != "" {
+				fields = append(fields, emailField{email: v, instancePtr: instancePtr(pointer)})
+			}
+		case []interface{}:
+			for i, item := range v {
+				if email, ok := item.(string); ok && email != "" {
+					itemPointer := pointer + "/" + strconv.Itoa(i)
+					fields = append(fields, emailField{email: email, instancePtr: instancePtr(itemPointer)})
+				}
+			}
+		}
 	}
+	return fields
 }
@@ -0,0 +1,334 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/resolver"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/service"
+)
+
+// validate posts body to h and decodes the webhook response
+func validate(t *testing.T, h *ValidateHandler, target, body string) (int, domain.OryWebhookResponse) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.Handle(rec, httptest.NewRequest(http.MethodPost, target, strings.NewReader(body)))
+
+	var resp domain.OryWebhookResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body, err)
+	}
+	return rec.Code, resp
+}
+
+func TestValidateMessageTypes(t *testing.T) {
+	scheme, err := domain.NewMessageScheme(
+		map[string]int{domain.OutcomeDisposable: 4100001},
+		map[string]string{domain.LocalPartRoleAccount: domain.MessageTypeInfo},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewValidateHandler(newTestService(t, service.Options{}), []string{"/emails"}, nil, nil, scheme, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+		wantIDs    []int
+		wantTypes  []string
+	}{
+		{"allowed", "/", `{"emails": ["a@example.com"]}`, http.StatusOK, nil, nil},
+		{"remapped ID", "/", `{"emails": ["a@tempmail.com"]}`, http.StatusBadRequest, []int{4100001}, []string{domain.MessageTypeError}},
+		{"info only", "/?reject=role_account", `{"emails": ["admin@example.com"]}`, http.StatusOK,
+			[]int{domain.MessageIDRoleAccount}, []string{domain.MessageTypeInfo}},
+		{"info and error", "/?reject=role_account", `{"emails": ["admin@example.com", "a@tempmail.com"]}`, http.StatusBadRequest,
+			[]int{domain.MessageIDRoleAccount, 4100001}, []string{domain.MessageTypeInfo, domain.MessageTypeError}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := validate(t, h, tt.target, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if len(resp.Messages) != len(tt.wantIDs) {
+				t.Fatalf("messages = %+v, want %d groups", resp.Messages, len(tt.wantIDs))
+			}
+			for i, group := range resp.Messages {
+				msg := group.Messages[0]
+				if msg.ID != tt.wantIDs[i] || msg.Type != tt.wantTypes[i] {
+					t.Errorf("message %d = %d/%s, want %d/%s", i, msg.ID, msg.Type, tt.wantIDs[i], tt.wantTypes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFailClosed(t *testing.T) {
+	log := slog.New(slog.DiscardHandler)
+	svc := service.NewDisposableEmailService(service.Options{
+		ListURLs:      []string{"/nonexistent"},
+		FailurePolicy: service.FailurePolicyClosed,
+		Overrides:     service.Overrides{DenyDomains: []string{"denied.com"}},
+	}, log)
+	if _, err := svc.Refresh(); err == nil {
+		t.Fatal("Refresh() of a missing file = nil error")
+	}
+	h := NewValidateHandler(svc, []string{"/emails"}, nil, nil, domain.MessageScheme{}, log)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantIDs    []int
+	}{
+		{"unavailable", `{"emails": ["a@example.com"]}`, http.StatusServiceUnavailable, []int{domain.MessageIDUnavailable}},
+		// A definite rejection is not something to retry
+		{"unavailable and denied", `{"emails": ["a@example.com", "b@denied.com"]}`, http.StatusBadRequest,
+			[]int{domain.MessageIDUnavailable, domain.MessageIDBlocked}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := validate(t, h, "/", tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if len(resp.Messages) != len(tt.wantIDs) {
+				t.Fatalf("messages = %+v, want %d groups", resp.Messages, len(tt.wantIDs))
+			}
+			for i, group := range resp.Messages {
+				if id := group.Messages[0].ID; id != tt.wantIDs[i] {
+					t.Errorf("message %d ID = %d, want %d", i, id, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+
+	batch := NewBatchHandler(svc, 10, log)
+	rec := httptest.NewRecorder()
+	batch.Handle(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`["a@example.com"]`)))
+	var resp BatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].Verdict != batchVerdictUnavailable {
+		t.Errorf("batch results = %+v, want %s", resp.Results, batchVerdictUnavailable)
+	}
+}
+
+func TestValidateKratosPayload(t *testing.T) {
+	h := NewValidateHandler(newTestService(t, service.Options{}), []string{"/email", "/identity/traits/email"}, nil, nil,
+		domain.MessageScheme{}, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantPtr    string
+		wantID     int
+	}{
+		{"custom body", http.MethodPost, `{"email": "a@tempmail.com"}`, http.StatusBadRequest, "#/traits/email", domain.MessageIDDisposable},
+		{"kratos context", http.MethodPost, `{
+			"identity": {"id": "9f425a8d", "schema_id": "default", "traits": {"email": "a@sub.tempmail.com", "name": {"first": "A"}}},
+			"flow": {"id": "f1", "type": "browser", "ui": {"nodes": []}},
+			"request_headers": {"Accept-Language": ["de"]},
+			"request_url": "https://auth.example.com/self-service/registration"
+		}`, http.StatusBadRequest, "#/traits/email", domain.MessageIDDisposable},
+		{"allowed", http.MethodPost, `{"identity": {"traits": {"email": "a@example.com"}}}`, http.StatusOK, "", 0},
+		{"invalid syntax", http.MethodPost, `{"email": "not an email"}`, http.StatusBadRequest, "#/traits/email", domain.OutcomeMessageIDs()["syntax.missing_at"]},
+		{"no email", http.MethodPost, `{"identity": {"traits": {"phone": "+123"}}}`, http.StatusBadRequest, "#/", domain.RequestErrorID(http.StatusBadRequest)},
+		{"email not a string", http.MethodPost, `{"email": 42}`, http.StatusBadRequest, "#/", domain.RequestErrorID(http.StatusBadRequest)},
+		{"malformed body", http.MethodPost, `{"email": `, http.StatusBadRequest, "#/", domain.RequestErrorID(http.StatusBadRequest)},
+		{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed, "#/", domain.RequestErrorID(http.StatusMethodNotAllowed)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.Handle(rec, httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body)))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			var resp domain.OryWebhookResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantID == 0 {
+				if len(resp.Messages) != 0 {
+					t.Errorf("messages = %+v, want none", resp.Messages)
+				}
+				return
+			}
+			if len(resp.Messages) != 1 {
+				t.Fatalf("messages = %+v, want one group", resp.Messages)
+			}
+			group := resp.Messages[0]
+			if group.InstancePtr != tt.wantPtr || group.Messages[0].ID != tt.wantID {
+				t.Errorf("message = %s %d, want %s %d", group.InstancePtr, group.Messages[0].ID, tt.wantPtr, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestValidateMultipleEmails(t *testing.T) {
+	h := NewValidateHandler(newTestService(t, service.Options{}),
+		[]string{"/identity/traits/email", "/identity/traits/recovery_email", "/identity/traits/emails"}, nil, nil,
+		domain.MessageScheme{}, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		name       string
+		traits     string
+		wantStatus int
+		wantPtrs   []string
+	}{
+		{"all allowed", `{"email": "a@example.com", "recovery_email": "b@example.org", "emails": ["c@example.net"]}`, http.StatusOK, nil},
+		{"recovery email", `{"email": "a@example.com", "recovery_email": "b@tempmail.com"}`, http.StatusBadRequest,
+			[]string{"#/traits/recovery_email"}},
+		{"each offending field", `{"email": "a@mailinator.com", "recovery_email": "b@example.org", "emails": ["c@example.net", "d@tempmail.com", "oops"]}`,
+			http.StatusBadRequest, []string{"#/traits/email", "#/traits/emails/1", "#/traits/emails/2"}},
+		// Empty and non-string items are not addresses to check
+		{"skipped items", `{"email": "", "emails": [42, null, "a@example.com"]}`, http.StatusOK, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := validate(t, h, "/", `{"identity": {"traits": `+tt.traits+`}}`)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			var ptrs []string
+			for _, group := range resp.Messages {
+				ptrs = append(ptrs, group.InstancePtr)
+			}
+			if !slices.Equal(ptrs, tt.wantPtrs) {
+				t.Errorf("instance pointers = %q, want %q", ptrs, tt.wantPtrs)
+			}
+		})
+	}
+}
+
+func TestValidateMetrics(t *testing.T) {
+	h := NewValidateHandler(newTestService(t, service.Options{}), []string{"/emails"}, nil, nil,
+		domain.MessageScheme{}, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		email   string
+		verdict string
+	}{
+		{"a@example.com", metrics.VerdictAllowed},
+		{"a@tempmail.com", metrics.VerdictDisposable},
+		{"not an email", metrics.VerdictInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.verdict, func(t *testing.T) {
+			counter := metrics.Validations.WithLabelValues(tt.verdict)
+			before := testutil.ToFloat64(counter)
+			validate(t, h, "/", `{"emails": ["`+tt.email+`"]}`)
+			if delta := testutil.ToFloat64(counter) - before; delta != 1 {
+				t.Errorf("%s validations went up by %v, want 1", tt.verdict, delta)
+			}
+		})
+	}
+}
+
+func TestValidateHostMatchContext(t *testing.T) {
+	f := resolver.NewFake()
+	f.MX["rotating.test"] = []*net.MX{{Host: "mx.rotating.test.", Pref: 10}}
+	f.Hosts["mx.rotating.test"] = []string{"203.0.113.25"}
+	f.MX["parked.test"] = []*net.MX{{Host: "mx.parked.test.", Pref: 10}}
+	f.NS["parked.test"] = []*net.NS{{Host: "ns1.tempdns.net."}}
+
+	dir := t.TempDir()
+	mxList, nsList := filepath.Join(dir, "mx.txt"), filepath.Join(dir, "ns.txt")
+	if err := os.WriteFile(mxList, []byte("203.0.113.0/24\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nsList, []byte("tempdns.net\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestService(t, service.Options{DNS: service.DNSOptions{Resolver: f, MXListURLs: []string{mxList}, NSListURLs: []string{nsList}}})
+	h := NewValidateHandler(svc, []string{"/email"}, nil, nil, domain.MessageScheme{}, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		email       string
+		wantContext map[string]interface{}
+	}{
+		{"a@rotating.test", map[string]interface{}{"source": domain.SourceMX, "mx_host": "mx.rotating.test",
+			"matched_domain": "203.0.113.0/24", "matched_ip": "203.0.113.25"}},
+		{"a@parked.test", map[string]interface{}{"source": domain.SourceNS, "ns_host": "ns1.tempdns.net", "matched_domain": "tempdns.net"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			status, resp := validate(t, h, "/", `{"email": "`+tt.email+`"}`)
+			if status != http.StatusBadRequest || len(resp.Messages) != 1 {
+				t.Fatalf("status = %d, messages = %+v; want one rejection", status, resp.Messages)
+			}
+			msg := resp.Messages[0].Messages[0]
+			if msg.ID != domain.MessageIDDisposable {
+				t.Errorf("message ID = %d, want %d", msg.ID, domain.MessageIDDisposable)
+			}
+			for key, want := range tt.wantContext {
+				if got := msg.Context[key]; got != want {
+					t.Errorf("context[%s] = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateFreeMail(t *testing.T) {
+	h := NewValidateHandler(newTestService(t, service.Options{}), []string{"/email"}, nil, nil,
+		domain.MessageScheme{}, slog.New(slog.DiscardHandler))
+
+	tests := []struct {
+		name       string
+		target     string
+		email      string
+		wantStatus int
+		wantID     int
+	}{
+		// B2C hooks only reject disposable addresses
+		{"allowed by default", "/", "a@gmail.com", http.StatusOK, 0},
+		{"rejected when selected", "/?reject=free_mail", "a@gmail.com", http.StatusBadRequest, domain.MessageIDFreeMail},
+		{"repeated parameter", "/?reject=disposable&reject=free_mail", "a@googlemail.com", http.StatusBadRequest, domain.MessageIDFreeMail},
+		{"disposable keeps its message", "/?reject=free_mail", "a@tempmail.com", http.StatusBadRequest, domain.MessageIDDisposable},
+		{"unknown category", "/?reject=free_mail,spam", "a@gmail.com", http.StatusBadRequest, domain.RequestErrorID(http.StatusBadRequest)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := validate(t, h, tt.target, `{"email": "`+tt.email+`"}`)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d", status, tt.wantStatus)
+			}
+			if tt.wantID == 0 {
+				if len(resp.Messages) != 0 {
+					t.Errorf("messages = %+v, want none", resp.Messages)
+				}
+				return
+			}
+			if len(resp.Messages) != 1 {
+				t.Fatalf("messages = %+v, want one group", resp.Messages)
+			}
+			msg := resp.Messages[0].Messages[0]
+			if msg.ID != tt.wantID {
+				t.Errorf("message ID = %d, want %d", msg.ID, tt.wantID)
+			}
+			if tt.wantID == domain.MessageIDFreeMail && msg.Context["source"] != domain.SourceFreeMail {
+				t.Errorf("context = %v, want source %s", msg.Context, domain.SourceFreeMail)
+			}
+		})
+	}
+}
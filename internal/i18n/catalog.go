@@ -0,0 +1,202 @@
+// Package i18n translates the webhook messages with per-locale catalogs of
+// Go templates keyed by message ID.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"golang.org/x/text/language"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// Catalog holds the message templates of every configured locale. A nil
+// Catalog leaves messages untouched.
+type Catalog struct {
+	defaultLocale language.Tag
+	// tags lists the locales with a catalog, the default locale first
+	tags    []language.Tag
+	matcher language.Matcher
+	// messages maps a locale and a message ID to its template
+	messages map[string]map[int]*template.Template
+}
+
+// Load reads one catalog per "<locale>.json" file in dir, e.g. "de.json" or
+// "pt-BR.json". A catalog is a JSON object mapping default message IDs or
+// outcome names (see domain.Outcomes) to templates:
+//
+//	{"4000001": "Wegwerf-Adressen von {{.domain}} sind nicht erlaubt",
+//	 "syntax.missing_at": "Die E-Mail-Adresse muss ein \"@\" enthalten"}
+//
+// Templates are executed with the message context (see
+// domain.MessageContextKeys; absent keys are empty). Unknown IDs, unknown
+// context keys and templates that fail to parse or execute are reported as
+// errors. Messages missing from a catalog fall back to the default locale,
+// then to the built-in English text.
+func Load(dir, defaultLocale string) (*Catalog, error) {
+	def, err := language.Parse(defaultLocale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default locale %q: %w", defaultLocale, err)
+	}
+
+	c := &Catalog{
+		defaultLocale: def,
+		messages:      make(map[string]map[int]*template.Template),
+	}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list catalogs: %w", err)
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			locale := strings.TrimSuffix(filepath.Base(file), ".json")
+			tag, err := language.Parse(locale)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid locale %q: %w", file, locale, err)
+			}
+			if _, exists := c.messages[tag.String()]; exists {
+				return nil, fmt.Errorf("%s: duplicate catalog for locale %s", file, tag)
+			}
+			messages, err := loadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			c.messages[tag.String()] = messages
+			if tag != def {
+				c.tags = append(c.tags, tag)
+			}
+		}
+	}
+
+	// The default locale always matches, with or without a catalog
+	c.tags = append([]language.Tag{def}, c.tags...)
+	c.matcher = language.NewMatcher(c.tags)
+	return c, nil
+}
+
+// loadFile parses and validates a single catalog
+func loadFile(file string) (map[int]*template.Template, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+	var raw map[string]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to decode catalog: %w", err)
+	}
+
+	known := domain.MessageIDs()
+	outcomes := domain.OutcomeMessageIDs()
+	sample := contextData(nil)
+	messages := make(map[int]*template.Template, len(raw))
+	for key, text := range raw {
+		id, ok := outcomes[key]
+		if !ok {
+			var err error
+			id, err = strconv.Atoi(key)
+			if err != nil || !slices.Contains(known, id) {
+				return nil, fmt.Errorf("unknown message ID %q", key)
+			}
+		}
+		if _, exists := messages[id]; exists {
+			return nil, fmt.Errorf("message %s: duplicate of message %d", key, id)
+		}
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("message %s: %w", key, err)
+		}
+		if err := tmpl.Execute(new(strings.Builder), sample); err != nil {
+			return nil, fmt.Errorf("message %s: %w", key, err)
+		}
+		messages[id] = tmpl
+	}
+	return messages, nil
+}
+
+// contextData returns the template data for a message context: every known
+// key, empty unless set in ctx
+func contextData(ctx map[string]interface{}) map[string]interface{} {
+	data := make(map[string]interface{}, len(domain.MessageContextKeys))
+	for _, key := range domain.MessageContextKeys {
+		data[key] = ""
+	}
+	for key, value := range ctx {
+		data[key] = value
+	}
+	return data
+}
+
+// Match picks the supported locale that best fits the preferred locales,
+// most preferred first; it returns the default locale when none fits
+func (c *Catalog) Match(preferred ...language.Tag) language.Tag {
+	if c == nil || len(preferred) == 0 {
+		return c.fallback()
+	}
+	_, index, confidence := c.matcher.Match(preferred...)
+	if confidence == language.No {
+		return c.defaultLocale
+	}
+	return c.tags[index]
+}
+
+// fallback returns the default locale, or English for a nil Catalog
+func (c *Catalog) fallback() language.Tag {
+	if c == nil {
+		return language.English
+	}
+	return c.defaultLocale
+}
+
+// Translate rewrites the text of every message in groups for locale
+func (c *Catalog) Translate(groups []domain.MessageGroup, locale language.Tag) {
+	if c == nil {
+		return
+	}
+	for i := range groups {
+		for j := range groups[i].Messages {
+			msg := &groups[i].Messages[j]
+			if text, ok := c.render(locale, msg); ok {
+				msg.Text = text
+			}
+		}
+	}
+}
+
+// render executes the template for msg in locale, falling back to the
+// default locale
+func (c *Catalog) render(locale language.Tag, msg *domain.Message) (string, bool) {
+	for _, tag := range []language.Tag{locale, c.defaultLocale} {
+		tmpl, ok := c.messages[tag.String()][msg.ID]
+		if !ok {
+			continue
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, contextData(msg.Context)); err != nil {
+			continue
+		}
+		return b.String(), true
+	}
+	return "", false
+}
+
+// Locales returns the locales with a catalog, the default locale first
+func (c *Catalog) Locales() []string {
+	if c == nil {
+		return nil
+	}
+	locales := make([]string, 0, len(c.tags))
+	for _, tag := range c.tags {
+		locales = append(locales, tag.String())
+	}
+	return locales
+}
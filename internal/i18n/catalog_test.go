@@ -0,0 +1,148 @@
+package i18n
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// writeCatalogs writes one file per name into a new directory
+func writeCatalogs(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeCatalogs(t, map[string]string{
+		"en.json":    `{"disposable": "No throwaway addresses from {{.domain}}"}`,
+		"de.json":    `{"4000001": "Wegwerf-Adressen von {{.domain}} sind nicht erlaubt", "syntax.missing_at": "Die E-Mail-Adresse muss ein \"@\" enthalten"}`,
+		"pt-BR.json": `{}`,
+		"notes.txt":  `ignored`,
+	})
+	c, err := Load(dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.Locales(), []string{"en", "de", "pt-BR"}; !slices.Equal(got, want) {
+		t.Errorf("Locales() = %q, want %q", got, want)
+	}
+
+	missingAt := domain.OutcomeMessageIDs()["syntax.missing_at"]
+	tests := []struct {
+		name   string
+		locale language.Tag
+		id     int
+		want   string
+	}{
+		{"translated", language.German, domain.MessageIDDisposable, "Wegwerf-Adressen von tempmail.com sind nicht erlaubt"},
+		{"by outcome name", language.German, missingAt, `Die E-Mail-Adresse muss ein "@" enthalten`},
+		{"default locale", language.English, domain.MessageIDDisposable, "No throwaway addresses from tempmail.com"},
+		// Missing from pt-BR: the default locale's template
+		{"fallback to default locale", language.MustParse("pt-BR"), domain.MessageIDDisposable, "No throwaway addresses from tempmail.com"},
+		// Missing everywhere: the built-in text is kept
+		{"fallback to built-in", language.German, domain.MessageIDUndeliverable, "built-in"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups := []domain.MessageGroup{{Messages: []domain.Message{{
+				ID:      tt.id,
+				Text:    "built-in",
+				Context: map[string]interface{}{"domain": "tempmail.com"},
+			}}}}
+			c.Translate(groups, tt.locale)
+			if got := groups[0].Messages[0].Text; got != tt.want {
+				t.Errorf("Text = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	dir := writeCatalogs(t, map[string]string{"de.json": `{}`, "pt-BR.json": `{}`})
+	c, err := Load(dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		preferred []string
+		want      string
+	}{
+		{[]string{"de-AT"}, "de"},
+		{[]string{"pt-BR"}, "pt-BR"},
+		{[]string{"fr", "de"}, "de"},
+		{[]string{"ja"}, "en"},
+		{nil, "en"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.preferred, ","), func(t *testing.T) {
+			var tags []language.Tag
+			for _, p := range tt.preferred {
+				tags = append(tags, language.MustParse(p))
+			}
+			if got := c.Match(tags...); got.String() != tt.want {
+				t.Errorf("Match(%q) = %s, want %s", tt.preferred, got, tt.want)
+			}
+		})
+	}
+
+	var nilCatalog *Catalog
+	if got := nilCatalog.Match(language.German); got != language.English {
+		t.Errorf("nil Catalog Match() = %s, want en", got)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		locale  string
+		wantErr string
+	}{
+		{"invalid default locale", nil, "not a locale!", "invalid default locale"},
+		{"invalid file locale", map[string]string{"xx-invalid-.json": `{}`}, "en", "invalid locale"},
+		{"duplicate locale", map[string]string{"de.json": `{}`, "DE.json": `{}`}, "en", "duplicate catalog"},
+		{"malformed json", map[string]string{"de.json": `{"4000001": `}, "en", "failed to decode"},
+		{"unknown ID", map[string]string{"de.json": `{"1234": "x"}`}, "en", "unknown message ID"},
+		{"unknown outcome", map[string]string{"de.json": `{"spam": "x"}`}, "en", "unknown message ID"},
+		{"duplicate message", map[string]string{"de.json": `{"4000001": "a", "disposable": "b"}`}, "en", "duplicate of message"},
+		{"bad template", map[string]string{"de.json": `{"4000001": "{{.domain"}`}, "en", "message 4000001"},
+		{"unknown context key", map[string]string{"de.json": `{"4000001": "{{.nope}}"}`}, "en", "nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := ""
+			if tt.files != nil {
+				dir = writeCatalogs(t, tt.files)
+			}
+			_, err := Load(dir, tt.locale)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNilCatalog(t *testing.T) {
+	var c *Catalog
+	groups := []domain.MessageGroup{{Messages: []domain.Message{{ID: domain.MessageIDDisposable, Text: "built-in"}}}}
+	c.Translate(groups, language.German)
+	if got := groups[0].Messages[0].Text; got != "built-in" {
+		t.Errorf("Text = %q, want the built-in text", got)
+	}
+	if got := c.Locales(); got != nil {
+		t.Errorf("Locales() = %q, want nil", got)
+	}
+}
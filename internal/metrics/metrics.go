@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "kratos_disposable"
+
+// Verdict label values for Validations and BatchValidations
+const (
+	VerdictAllowed          = "allowed"
+	VerdictDisposable       = "disposable"
+	VerdictInvalid          = "invalid"
+	VerdictFailOpen         = "fail_open"
+	VerdictFailClosed       = "fail_closed"
+	VerdictUndeliverable    = "undeliverable"
+	VerdictFlagged          = "flagged"
+	VerdictRisky            = "risky"
+	VerdictFreeMail         = "free_mail"
+	VerdictRoleAccount      = "role_account"
+	VerdictRandomLocalPart  = "random_local_part"
+	VerdictLocalPartPattern = "local_part_pattern"
+)
+
+// DNS lookup result label values for DNSLookups
+const (
+	DNSFound    = "found"
+	DNSNotFound = "not_found"
+	DNSError    = "error"
+)
+
+var (
+	// Validations counts email addresses checked by the webhook by verdict
+	Validations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "validations_total",
+		Help:      "Email validations by verdict.",
+	}, []string{"verdict"})
+
+	// BatchValidations counts addresses checked by the batch endpoint by
+	// verdict, kept apart from Validations so backfills do not skew the
+	// webhook figures
+	BatchValidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "batch_validations_total",
+		Help:      "Email validations by the batch endpoint by verdict.",
+	}, []string{"verdict"})
+
+	// RefreshAttempts counts list fetches per source. Sources are labelled by
+	// list and position ("list/0", "mx/1"), never by URL.
+	RefreshAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_attempts_total",
+		Help:      "List fetch attempts per source.",
+	}, []string{"source"})
+
+	// RefreshSuccesses counts successful list fetches per source, including 304s
+	RefreshSuccesses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_successes_total",
+		Help:      "Successful list fetches per source (including not modified).",
+	}, []string{"source"})
+
+	// RefreshFailures counts failed list fetches per source
+	RefreshFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_failures_total",
+		Help:      "Failed list fetches per source.",
+	}, []string{"source"})
+
+	// NotModified counts fetches answered with 304 Not Modified per source
+	NotModified = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "refresh_not_modified_total",
+		Help:      "List fetches answered with 304 Not Modified per source.",
+	}, []string{"source"})
+
+	// DNSLookups counts DNS lookups by record type ("mx", "ns", "host") and result
+	DNSLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "dns_lookups_total",
+		Help:      "DNS lookups by record type and result.",
+	}, []string{"type", "result"})
+
+	// HTTPRequestDuration observes HTTP request latency
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// ListState exposes the loaded list to the gauges registered by RegisterListGauges
+type ListState interface {
+	DomainCount() int
+	ListAge() time.Duration
+}
+
+// RegisterListGauges registers gauges reporting the current domain count and
+// the age of the loaded list
+func RegisterListGauges(state ListState) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "domains",
+		Help:      "Number of disposable domains currently loaded.",
+	}, func() float64 {
+		return float64(state.DomainCount())
+	})
+
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "list_age_seconds",
+		Help:      "Seconds since the list was last refreshed successfully (since startup if never).",
+	}, func() float64 {
+		return state.ListAge().Seconds()
+	})
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -31,7 +31,7 @@ func (m *AuthMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 				slog.String("path", r.URL.Path),
 				slog.String("method", r.Method),
 				slog.String("ip", r.RemoteAddr))
-			respondError(w, http.StatusUnauthorized, "Missing API key")
+			m.respondError(w, http.StatusUnauthorized, "Missing API key")
 			return
 		}
 
@@ -40,7 +40,7 @@ func (m *AuthMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 				slog.String("path", r.URL.Path),
 				slog.String("method", r.Method),
 				slog.String("ip", r.RemoteAddr))
-			respondError(w, http.StatusUnauthorized, "Invalid API key")
+			m.respondError(w, http.StatusUnauthorized, "Invalid API key")
 			return
 		}
 
@@ -48,25 +48,12 @@ func (m *AuthMiddleware) Authenticate(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
-// respondError sends a JSON error response
-func respondError(w http.ResponseWriter, statusCode int, message string) {
+// respondError sends an error in the same format as the handlers
+func (m *AuthMiddleware) respondError(w http.ResponseWriter, statusCode int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
-	resp := domain.OryWebhookResponse{
-		Messages: []domain.MessageGroup{
-			{
-				InstancePtr: "#/",
-				Messages: []domain.Message{
-					{
-						ID:   statusCode,
-						Text: message,
-						Type: "error",
-					},
-				},
-			},
-		},
+	if err := json.NewEncoder(w).Encode(domain.NewRequestErrorResponse(statusCode, message)); err != nil {
+		m.logger.Error("failed to encode response", slog.Any("error", err))
 	}
-
-	json.NewEncoder(w).Encode(resp)
 }
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+func TestAuthenticate(t *testing.T) {
+	m := NewAuthMiddleware("secret", slog.New(slog.DiscardHandler))
+	next := m.Authenticate(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+		wantText   string
+	}{
+		{"valid key", "secret", http.StatusNoContent, ""},
+		{"missing key", "", http.StatusUnauthorized, "Missing API key"},
+		{"wrong key", "secrets", http.StatusUnauthorized, "Invalid API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/validate/email", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			rec := httptest.NewRecorder()
+			next(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantText == "" {
+				return
+			}
+			var resp domain.OryWebhookResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			msg := resp.Messages[0].Messages[0]
+			if msg.ID != domain.RequestErrorID(http.StatusUnauthorized) || msg.Text != tt.wantText || msg.Type != domain.MessageTypeError {
+				t.Errorf("message = %+v, want %q", msg, tt.wantText)
+			}
+		})
+	}
+}
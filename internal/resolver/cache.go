@@ -0,0 +1,118 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a cached lookup result. Not-found errors are cached too;
+// other errors (timeouts, server failures) never are.
+type cacheEntry struct {
+	mx      []*net.MX
+	ns      []*net.NS
+	hosts   []string
+	err     error
+	expires time.Time
+}
+
+// CachingResolver caches the answers of another resolver
+type CachingResolver struct {
+	next        Resolver
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCachingResolver caches answers from next for ttl, and not-found answers
+// for negativeTTL. At most maxEntries answers are kept; zero disables caching.
+func NewCachingResolver(next Resolver, ttl, negativeTTL time.Duration, maxEntries int) *CachingResolver {
+	return &CachingResolver{
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]cacheEntry),
+	}
+}
+
+func (c *CachingResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	key := "mx:" + name
+	if entry, ok := c.get(key); ok {
+		return entry.mx, entry.err
+	}
+	mx, err := c.next.LookupMX(ctx, name)
+	c.put(key, cacheEntry{mx: mx, err: err})
+	return mx, err
+}
+
+func (c *CachingResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	key := "ns:" + name
+	if entry, ok := c.get(key); ok {
+		return entry.ns, entry.err
+	}
+	ns, err := c.next.LookupNS(ctx, name)
+	c.put(key, cacheEntry{ns: ns, err: err})
+	return ns, err
+}
+
+func (c *CachingResolver) LookupHost(ctx context.Context, name string) ([]string, error) {
+	key := "host:" + name
+	if entry, ok := c.get(key); ok {
+		return entry.hosts, entry.err
+	}
+	hosts, err := c.next.LookupHost(ctx, name)
+	c.put(key, cacheEntry{hosts: hosts, err: err})
+	return hosts, err
+}
+
+// get returns an unexpired entry
+func (c *CachingResolver) get(key string) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put stores an answer, evicting expired entries (or arbitrary ones) when full
+func (c *CachingResolver) put(key string, entry cacheEntry) {
+	ttl := c.ttl
+	if entry.err != nil {
+		if !IsNotFound(entry.err) {
+			return
+		}
+		ttl = c.negativeTTL
+	}
+	if ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+	entry.expires = time.Now().Add(ttl)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		// Map iteration order is random, which makes this a random eviction
+		for k := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry
+}
@@ -0,0 +1,92 @@
+package resolver
+
+import (
+	"context"
+	"net"
+	"sync"
+)
+
+// Fake is an in-memory Resolver for tests. Names missing from all record
+// maps resolve as not found (NXDOMAIN); names present in one map, even with
+// no records, answer the other lookups with ErrNoData (NODATA). Names in
+// Errors fail with that error.
+type Fake struct {
+	mu     sync.Mutex
+	MX     map[string][]*net.MX
+	NS     map[string][]*net.NS
+	Hosts  map[string][]string
+	Errors map[string]error
+	// Lookups counts the lookups per "mx:<name>", "ns:<name>" or
+	// "host:<name>" key
+	Lookups map[string]int
+}
+
+// NewFake creates an empty fake resolver
+func NewFake() *Fake {
+	return &Fake{
+		MX:      make(map[string][]*net.MX),
+		NS:      make(map[string][]*net.NS),
+		Hosts:   make(map[string][]string),
+		Errors:  make(map[string]error),
+		Lookups: make(map[string]int),
+	}
+}
+
+func (f *Fake) LookupMX(_ context.Context, name string) ([]*net.MX, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Lookups["mx:"+name]++
+	if err, ok := f.Errors[name]; ok {
+		return nil, err
+	}
+	if mx := f.MX[name]; len(mx) > 0 {
+		return mx, nil
+	}
+	return nil, f.notFoundLocked(name)
+}
+
+func (f *Fake) LookupNS(_ context.Context, name string) ([]*net.NS, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Lookups["ns:"+name]++
+	if err, ok := f.Errors[name]; ok {
+		return nil, err
+	}
+	if ns := f.NS[name]; len(ns) > 0 {
+		return ns, nil
+	}
+	return nil, f.notFoundLocked(name)
+}
+
+func (f *Fake) LookupHost(_ context.Context, name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.Lookups["host:"+name]++
+	if err, ok := f.Errors[name]; ok {
+		return nil, err
+	}
+	if hosts := f.Hosts[name]; len(hosts) > 0 {
+		return hosts, nil
+	}
+	return nil, f.notFoundLocked(name)
+}
+
+// notFoundLocked answers NODATA for names present in a record map and
+// NXDOMAIN otherwise
+func (f *Fake) notFoundLocked(name string) error {
+	_, mx := f.MX[name]
+	_, ns := f.NS[name]
+	_, hosts := f.Hosts[name]
+	if mx || ns || hosts {
+		return noData(name)
+	}
+	return notFound(name)
+}
+
+// notFound builds the error the standard library returns for missing names
+func notFound(name string) error {
+	return &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
@@ -0,0 +1,179 @@
+// Package resolver looks up the DNS records used to judge whether a mail
+// domain exists and which mail exchangers and nameservers serve it.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Resolver looks up mail exchanger, nameserver and address records
+type Resolver interface {
+	// LookupMX returns the MX records of name
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	// LookupNS returns the NS records of name
+	LookupNS(ctx context.Context, name string) ([]*net.NS, error)
+	// LookupHost returns the A and AAAA addresses of name
+	LookupHost(ctx context.Context, name string) ([]string, error)
+}
+
+// ErrNoData is wrapped by not-found errors for names that exist but have no
+// records of the requested type (NODATA), as opposed to names that do not
+// exist at all (NXDOMAIN)
+var ErrNoData = errors.New("no records of the requested type")
+
+// IsNotFound reports whether err means the name or the requested records do
+// not exist, as opposed to a timeout or server failure
+func IsNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// IsNoData reports whether err means the name exists but has no records of
+// the requested type
+func IsNoData(err error) bool {
+	return IsNotFound(err) && errors.Is(err, ErrNoData)
+}
+
+// noData builds the not-found error for a name without records of the
+// requested type
+func noData(name string) error {
+	return &net.DNSError{Err: "no such record", Name: name, IsNotFound: true, UnwrapErr: ErrNoData}
+}
+
+// resolvConf is where the system DNS servers are configured
+const resolvConf = "/etc/resolv.conf"
+
+// netResolver resolves through the standard library
+type netResolver struct {
+	resolver *net.Resolver
+	server   string
+	timeout  time.Duration
+}
+
+// NewNetResolver creates a resolver using the system configuration, or the
+// DNS server at server ("host:port") when it is not empty. Every lookup is
+// bounded by timeout.
+func NewNetResolver(server string, timeout time.Duration) Resolver {
+	r := &net.Resolver{}
+	if server != "" {
+		r.PreferGo = true
+		r.Dial = func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		}
+	}
+	return &netResolver{resolver: r, server: server, timeout: timeout}
+}
+
+func (r *netResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	mx, err := r.resolver.LookupMX(ctx, name)
+	return mx, r.notFound(ctx, name, err)
+}
+
+func (r *netResolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	ns, err := r.resolver.LookupNS(ctx, name)
+	return ns, r.notFound(ctx, name, err)
+}
+
+func (r *netResolver) LookupHost(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	hosts, err := r.resolver.LookupHost(ctx, name)
+	return hosts, r.notFound(ctx, name, err)
+}
+
+// notFound tells NODATA from NXDOMAIN for not-found errors, which the
+// standard library reports alike. When the name turns out to exist the
+// error is replaced by one wrapping ErrNoData; when that cannot be
+// determined the error is kept.
+func (r *netResolver) notFound(ctx context.Context, name string, err error) error {
+	if !IsNotFound(err) {
+		return err
+	}
+	if exists, qerr := r.nameExists(ctx, name); qerr == nil && exists {
+		return noData(name)
+	}
+	return err
+}
+
+// nameExists sends an SOA query for name and reports whether the answer was
+// anything but NXDOMAIN
+func (r *netResolver) nameExists(ctx context.Context, name string) (bool, error) {
+	qname, err := dnsmessage.NewName(strings.TrimSuffix(name, ".") + ".")
+	if err != nil {
+		return false, err
+	}
+	id := uint16(rand.Uint32())
+	query, err := (&dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: qname, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET}},
+	}).Pack()
+	if err != nil {
+		return false, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", r.dnsServer())
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(query); err != nil {
+		return false, err
+	}
+
+	buf := make([]byte, 512)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return false, err
+		}
+		var p dnsmessage.Parser
+		h, err := p.Start(buf[:n])
+		if err != nil || h.ID != id || !h.Response {
+			// Not the answer to our query; keep waiting
+			continue
+		}
+		switch h.RCode {
+		case dnsmessage.RCodeSuccess:
+			return true, nil
+		case dnsmessage.RCodeNameError:
+			return false, nil
+		default:
+			return false, fmt.Errorf("dns server answered %s", h.RCode)
+		}
+	}
+}
+
+// dnsServer returns the configured DNS server, or the first one of the
+// system configuration
+func (r *netResolver) dnsServer() string {
+	if r.server != "" {
+		return r.server
+	}
+	if data, err := os.ReadFile(resolvConf); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && fields[0] == "nameserver" {
+				return net.JoinHostPort(fields[1], "53")
+			}
+		}
+	}
+	return "127.0.0.1:53"
+}
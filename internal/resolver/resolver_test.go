@@ -0,0 +1,211 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// zone maps a lower-case name without the trailing dot to its MX hosts.
+// Names in the zone exist; all others answer NXDOMAIN.
+type zone map[string][]string
+
+// serveDNS answers queries on a local UDP socket from z and returns its address
+func serveDNS(t *testing.T, z zone) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp, err := z.answer(buf[:n]); err == nil {
+				_, _ = conn.WriteTo(resp, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+// answer builds the response to a packed query
+func (z zone) answer(query []byte) ([]byte, error) {
+	var p dnsmessage.Parser
+	h, err := p.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	q, err := p.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(q.Name.String(), "."))
+	mx, exists := z[name]
+	rcode := dnsmessage.RCodeSuccess
+	if !exists {
+		rcode = dnsmessage.RCodeNameError
+	}
+
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{
+		ID:                 h.ID,
+		Response:           true,
+		Authoritative:      true,
+		RecursionDesired:   h.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := b.Question(q); err != nil {
+		return nil, err
+	}
+	if err := b.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if q.Type == dnsmessage.TypeMX {
+		for _, host := range mx {
+			hdr := dnsmessage.ResourceHeader{Name: q.Name, Class: dnsmessage.ClassINET, TTL: 60}
+			if err := b.MXResource(hdr, dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName(host + ".")}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return b.Finish()
+}
+
+func TestNetResolverNotFound(t *testing.T) {
+	server := serveDNS(t, zone{
+		"mail.test":   {"mx1.mail.test"},
+		"nomail.test": nil,
+	})
+	r := NewNetResolver(server, 2*time.Second)
+
+	tests := []struct {
+		name         string
+		wantMX       int
+		wantNoData   bool
+		wantNotFound bool
+	}{
+		{"mail.test", 1, false, false},
+		// NODATA: the name exists without MX records
+		{"nomail.test", 0, true, true},
+		// NXDOMAIN
+		{"missing.test", 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mx, err := r.LookupMX(context.Background(), tt.name)
+			if len(mx) != tt.wantMX {
+				t.Errorf("LookupMX() = %d records, want %d", len(mx), tt.wantMX)
+			}
+			if got := IsNotFound(err); got != tt.wantNotFound {
+				t.Errorf("IsNotFound(%v) = %v, want %v", err, got, tt.wantNotFound)
+			}
+			if got := IsNoData(err); got != tt.wantNoData {
+				t.Errorf("IsNoData(%v) = %v, want %v", err, got, tt.wantNoData)
+			}
+		})
+	}
+}
+
+func TestNameExists(t *testing.T) {
+	server := serveDNS(t, zone{"exists.test": nil})
+	r := &netResolver{server: server, timeout: 2 * time.Second}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"exists.test", true},
+		{"EXISTS.test.", true},
+		{"missing.test", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			got, err := r.nameExists(ctx, tt.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("nameExists(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotFoundKeepsErrorWhenUndetermined(t *testing.T) {
+	// Nothing answers on this socket, so existence cannot be determined
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	defer conn.Close()
+	r := &netResolver{server: conn.LocalAddr().String()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	nxdomain := notFound("quiet.test")
+	if err := r.notFound(ctx, "quiet.test", nxdomain); err != nxdomain {
+		t.Errorf("notFound() = %v, want the original error", err)
+	}
+
+	other := errors.New("timeout")
+	if err := r.notFound(ctx, "quiet.test", other); err != other {
+		t.Errorf("notFound() = %v, want errors other than not found unchanged", err)
+	}
+}
+
+func TestFakeNotFound(t *testing.T) {
+	f := NewFake()
+	f.MX["mail.test"] = []*net.MX{{Host: "mx.mail.test.", Pref: 10}}
+	f.Hosts["web.test"] = []string{"192.0.2.1"}
+	f.MX["emptymx.test"] = nil
+	f.Errors["broken.test"] = &net.DNSError{Err: "server misbehaving", Name: "broken.test", IsTemporary: true}
+
+	tests := []struct {
+		name         string
+		wantErr      bool
+		wantNoData   bool
+		wantNotFound bool
+	}{
+		{"mail.test", false, false, false},
+		// Present in another map, or with an empty slice: NODATA
+		{"web.test", true, true, true},
+		{"emptymx.test", true, true, true},
+		{"missing.test", true, false, true},
+		{"broken.test", true, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.LookupMX(context.Background(), tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LookupMX() error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := IsNoData(err); got != tt.wantNoData {
+				t.Errorf("IsNoData() = %v, want %v", got, tt.wantNoData)
+			}
+			if got := IsNotFound(err); got != tt.wantNotFound {
+				t.Errorf("IsNotFound() = %v, want %v", got, tt.wantNotFound)
+			}
+		})
+	}
+	if got := f.Lookups["mx:mail.test"]; got != 1 {
+		t.Errorf("Lookups[mx:mail.test] = %d, want 1", got)
+	}
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// Email syntax modes decide how much of RFC 5321/5322 is accepted
+const (
+	// SyntaxModeStrict accepts only what mail providers hand out in practice:
+	// an ASCII dot-atom local part and a fully qualified host name
+	SyntaxModeStrict = "strict"
+	// SyntaxModeStandard accepts RFC 5321 mailboxes: dot-atom or quoted
+	// local parts (UTF-8 allowed per RFC 6531) and address literals such as
+	// "[192.0.2.1]" or "[IPv6:2001:db8::1]" (default)
+	SyntaxModeStandard = "standard"
+	// SyntaxModeLenient additionally accepts RFC 5322 comments and folding
+	// white space around dots and the "@", a trailing dot on the domain and
+	// unqualified domains
+	SyntaxModeLenient = "lenient"
+)
+
+// Length limits from RFC 5321 section 4.5.3.1, with the overall limit from
+// the RFC 3696 errata
+const (
+	maxAddressLength   = 254
+	maxLocalPartLength = 64
+)
+
+// Address is a parsed email address
+type Address struct {
+	// LocalPart is the part before the "@", with quoting removed
+	LocalPart string
+	// Domain is the canonical ASCII domain, or the normalized address
+	// literal including brackets
+	Domain string
+	// IPLiteral is set when Domain is an address literal
+	IPLiteral bool
+}
+
+// syntaxError creates a *domain.SyntaxError
+func syntaxError(reason, detail string) error {
+	return &domain.SyntaxError{Reason: reason, Detail: detail}
+}
+
+// ParseAddress parses an email address according to mode (one of the
+// SyntaxMode* constants; empty means standard). Errors are *domain.SyntaxError.
+func ParseAddress(s, mode string) (Address, error) {
+	if mode == "" {
+		mode = SyntaxModeStandard
+	}
+	strict := mode == SyntaxModeStrict
+	lenient := mode == SyntaxModeLenient
+
+	s = strings.TrimSpace(s)
+	if lenient {
+		var err error
+		if s, err = stripCFWS(s); err != nil {
+			return Address{}, err
+		}
+	}
+	if s == "" {
+		return Address{}, syntaxError(domain.SyntaxEmpty, "")
+	}
+	if !utf8.ValidString(s) {
+		return Address{}, syntaxError(domain.SyntaxLocalPartCharacters, "invalid UTF-8")
+	}
+
+	// Local part
+	var addr Address
+	var localRaw, rest string
+	if s[0] == '"' {
+		if strict {
+			return Address{}, syntaxError(domain.SyntaxQuotedNotAllowed, "")
+		}
+		local, n, err := scanQuotedString(s)
+		if err != nil {
+			return Address{}, err
+		}
+		addr.LocalPart, localRaw, rest = local, s[:n], s[n:]
+		if rest == "" {
+			return Address{}, syntaxError(domain.SyntaxMissingAt, "")
+		}
+		if rest[0] != '@' {
+			return Address{}, syntaxError(domain.SyntaxLocalPartCharacters, "text after the quoted string")
+		}
+	} else {
+		at := strings.IndexByte(s, '@')
+		if at < 0 {
+			return Address{}, syntaxError(domain.SyntaxMissingAt, "")
+		}
+		localRaw, rest = s[:at], s[at:]
+		if err := checkDotAtom(localRaw, strict); err != nil {
+			return Address{}, err
+		}
+		addr.LocalPart = localRaw
+	}
+	if len(localRaw) > maxLocalPartLength {
+		return Address{}, syntaxError(domain.SyntaxLocalPartTooLong, fmt.Sprintf("%d characters", len(localRaw)))
+	}
+
+	// Domain
+	domainRaw := rest[1:]
+	switch {
+	case domainRaw == "":
+		return Address{}, syntaxError(domain.SyntaxEmptyDomain, "")
+	case strings.Contains(domainRaw, "@"):
+		return Address{}, syntaxError(domain.SyntaxMultipleAt, "")
+	}
+	if len(localRaw)+1+len(domainRaw) > maxAddressLength {
+		return Address{}, syntaxError(domain.SyntaxTooLong, fmt.Sprintf("%d characters", len(localRaw)+1+len(domainRaw)))
+	}
+
+	if domainRaw[0] == '[' {
+		if strict {
+			return Address{}, syntaxError(domain.SyntaxIPLiteralNotAllowed, "")
+		}
+		literal, err := parseAddressLiteral(domainRaw)
+		if err != nil {
+			return Address{}, err
+		}
+		addr.Domain, addr.IPLiteral = literal, true
+		return addr, nil
+	}
+
+	if strings.HasSuffix(domainRaw, ".") && !lenient {
+		return Address{}, syntaxError(domain.SyntaxInvalidDomain, "trailing dot")
+	}
+	d, err := canonicalDomain(domainRaw)
+	if errors.Is(err, errNotDomain) {
+		return Address{}, syntaxError(domain.SyntaxInvalidDomain, "")
+	}
+	if err != nil {
+		return Address{}, syntaxError(domain.SyntaxInvalidDomain, strings.TrimPrefix(err.Error(), "idna: "))
+	}
+	if !lenient {
+		if !strings.Contains(d, ".") {
+			return Address{}, syntaxError(domain.SyntaxDomainNotQualified, "")
+		}
+		if tld := d[strings.LastIndexByte(d, '.')+1:]; strings.Trim(tld, "0123456789") == "" {
+			return Address{}, syntaxError(domain.SyntaxInvalidDomain, "numeric top-level domain")
+		}
+	}
+	addr.Domain = d
+	return addr, nil
+}
+
+// isAtext reports whether r may appear in an atom (RFC 5322 section 3.2.3).
+// Non-ASCII characters are allowed unless asciiOnly is set (RFC 6531).
+func isAtext(r rune, asciiOnly bool) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	case strings.ContainsRune("!#$%&'*+-/=?^_`{|}~", r):
+		return true
+	case r >= utf8.RuneSelf:
+		return !asciiOnly && r != utf8.RuneError && r > 0x9f
+	}
+	return false
+}
+
+// checkDotAtom validates an unquoted local part
+func checkDotAtom(local string, asciiOnly bool) error {
+	if local == "" {
+		return syntaxError(domain.SyntaxEmptyLocalPart, "")
+	}
+	if local[0] == '.' || local[len(local)-1] == '.' || strings.Contains(local, "..") {
+		return syntaxError(domain.SyntaxLocalPartDots, "")
+	}
+	for _, r := range local {
+		switch {
+		case r == '.' || isAtext(r, asciiOnly):
+		case r == '(' || r == ')':
+			return syntaxError(domain.SyntaxComment, "")
+		case r == '"':
+			return syntaxError(domain.SyntaxLocalPartCharacters, "quotes must surround the whole local part")
+		default:
+			return syntaxError(domain.SyntaxLocalPartCharacters, fmt.Sprintf("%q", r))
+		}
+	}
+	return nil
+}
+
+// scanQuotedString reads the quoted string at the start of s (RFC 5321
+// Quoted-string) and returns its unescaped content and the number of bytes
+// consumed including both quotes
+func scanQuotedString(s string) (string, int, error) {
+	var b strings.Builder
+	escaped := false
+	for i, r := range s[1:] {
+		switch {
+		case escaped:
+			if r < ' ' || r == 0x7f {
+				return "", 0, syntaxError(domain.SyntaxLocalPartCharacters, fmt.Sprintf("%q", r))
+			}
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			if b.Len() == 0 {
+				return "", 0, syntaxError(domain.SyntaxEmptyLocalPart, "")
+			}
+			return b.String(), i + 2, nil
+		case r < ' ' || r == 0x7f || (r >= 0x80 && r <= 0x9f):
+			return "", 0, syntaxError(domain.SyntaxLocalPartCharacters, fmt.Sprintf("%q", r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", 0, syntaxError(domain.SyntaxUnterminatedQuote, "")
+}
+
+// parseAddressLiteral validates "[192.0.2.1]" or "[IPv6:2001:db8::1]" and
+// returns it in normalized form
+func parseAddressLiteral(s string) (string, error) {
+	if !strings.HasSuffix(s, "]") {
+		return "", syntaxError(domain.SyntaxInvalidIPLiteral, "missing \"]\"")
+	}
+	inner := s[1 : len(s)-1]
+
+	if v6, ok := cutPrefixFold(inner, "IPv6:"); ok {
+		ip := net.ParseIP(v6)
+		if ip == nil || (ip.To4() != nil && !strings.Contains(v6, ":")) {
+			return "", syntaxError(domain.SyntaxInvalidIPLiteral, inner)
+		}
+		return "[IPv6:" + ip.String() + "]", nil
+	}
+
+	ip := net.ParseIP(inner)
+	if ip == nil || ip.To4() == nil || strings.Contains(inner, ":") {
+		return "", syntaxError(domain.SyntaxInvalidIPLiteral, inner)
+	}
+	return "[" + ip.String() + "]", nil
+}
+
+// cutPrefixFold is strings.CutPrefix with a case-insensitive prefix
+func cutPrefixFold(s, prefix string) (string, bool) {
+	if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+		return s[len(prefix):], true
+	}
+	return s, false
+}
+
+// stripCFWS removes RFC 5322 comments ("(...)", possibly nested) and the
+// white space around dots and the "@" outside quoted strings, e.g.
+// "john (work) . doe @ example.com" becomes "john.doe@example.com"
+func stripCFWS(s string) (string, error) {
+	var out []rune
+	depth := 0
+	inQuote, escaped := false, false
+	for _, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+			if depth == 0 {
+				out = append(out, r)
+			}
+		case r == '\\' && (inQuote || depth > 0):
+			escaped = true
+			if depth == 0 {
+				out = append(out, r)
+			}
+		case inQuote:
+			inQuote = r != '"'
+			out = append(out, r)
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth == 0 {
+				return "", syntaxError(domain.SyntaxComment, "unbalanced parentheses")
+			}
+			depth--
+			if depth == 0 {
+				// A comment separates tokens like white space does
+				out = append(out, ' ')
+			}
+		case depth > 0:
+		case r == '"':
+			inQuote = true
+			out = append(out, r)
+		default:
+			out = append(out, r)
+		}
+	}
+	if depth > 0 {
+		return "", syntaxError(domain.SyntaxComment, "unbalanced parentheses")
+	}
+
+	// Drop white space next to a dot, an "@" or the ends, outside quotes
+	isSpace := func(r rune) bool { return r == ' ' || r == '\t' }
+	isSep := func(r rune) bool { return r == '.' || r == '@' }
+	var b strings.Builder
+	inQuote, escaped = false, false
+	for i, r := range out {
+		if inQuote || r == '"' || !isSpace(r) {
+			switch {
+			case escaped:
+				escaped = false
+			case inQuote && r == '\\':
+				escaped = true
+			case r == '"':
+				inQuote = !inQuote
+			}
+			b.WriteRune(r)
+			continue
+		}
+
+		prev := i - 1
+		for prev >= 0 && isSpace(out[prev]) {
+			prev--
+		}
+		next := i + 1
+		for next < len(out) && isSpace(out[next]) {
+			next++
+		}
+		if prev < 0 || next >= len(out) || isSep(out[prev]) || isSep(out[next]) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		email     string
+		mode      string
+		wantLocal string
+		wantHost  string
+		wantIP    bool
+	}{
+		{"john.doe@example.com", SyntaxModeStrict, "john.doe", "example.com", false},
+		{"  John+tag@Example.COM ", SyntaxModeStandard, "John+tag", "example.com", false},
+		{"user@bücher.de", SyntaxModeStrict, "user", "xn--bcher-kva.de", false},
+		{"user@r3---sn-abc.googlevideo.com", SyntaxModeStrict, "user", "r3---sn-abc.googlevideo.com", false},
+		{"user@ab--cd.com", SyntaxModeStrict, "user", "ab--cd.com", false},
+		{"user@ab--cd.com", SyntaxModeStandard, "user", "ab--cd.com", false},
+		{"user@a-b.com", SyntaxModeStrict, "user", "a-b.com", false},
+		{`"john doe"@example.com`, SyntaxModeStandard, "john doe", "example.com", false},
+		{`"a\"b"@example.com`, SyntaxModeStandard, `a"b`, "example.com", false},
+		{"用户@example.com", SyntaxModeStandard, "用户", "example.com", false},
+		{"user@[192.0.2.1]", SyntaxModeStandard, "user", "[192.0.2.1]", true},
+		{"user@[IPv6:2001:DB8::1]", SyntaxModeStandard, "user", "[IPv6:2001:db8::1]", true},
+		{"john (work) . doe @ example.com", SyntaxModeLenient, "john.doe", "example.com", false},
+		{"user@example.com.", SyntaxModeLenient, "user", "example.com", false},
+		{"user@localhost", SyntaxModeLenient, "user", "localhost", false},
+		// Empty mode is standard
+		{`"x y"@example.com`, "", "x y", "example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.email, func(t *testing.T) {
+			addr, err := ParseAddress(tt.email, tt.mode)
+			if err != nil {
+				t.Fatalf("ParseAddress() error = %v", err)
+			}
+			if addr.LocalPart != tt.wantLocal || addr.Domain != tt.wantHost || addr.IPLiteral != tt.wantIP {
+				t.Errorf("ParseAddress() = %+v, want local %q, domain %q, literal %v",
+					addr, tt.wantLocal, tt.wantHost, tt.wantIP)
+			}
+		})
+	}
+}
+
+func TestParseAddressReasons(t *testing.T) {
+	tests := []struct {
+		email string
+		mode  string
+		want  string
+	}{
+		{"", SyntaxModeStandard, domain.SyntaxEmpty},
+		{"   ", SyntaxModeStandard, domain.SyntaxEmpty},
+		{strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("b", 50)+".", 4) + "com", SyntaxModeStandard, domain.SyntaxTooLong},
+		{"user.example.com", SyntaxModeStandard, domain.SyntaxMissingAt},
+		{"a@b@example.com", SyntaxModeStandard, domain.SyntaxMultipleAt},
+		{"@example.com", SyntaxModeStandard, domain.SyntaxEmptyLocalPart},
+		{`""@example.com`, SyntaxModeStandard, domain.SyntaxEmptyLocalPart},
+		{strings.Repeat("a", 65) + "@example.com", SyntaxModeStandard, domain.SyntaxLocalPartTooLong},
+		{".user@example.com", SyntaxModeStandard, domain.SyntaxLocalPartDots},
+		{"user.@example.com", SyntaxModeStandard, domain.SyntaxLocalPartDots},
+		{"us..er@example.com", SyntaxModeStandard, domain.SyntaxLocalPartDots},
+		{"us er@example.com", SyntaxModeStandard, domain.SyntaxLocalPartCharacters},
+		{"us\"er@example.com", SyntaxModeStandard, domain.SyntaxLocalPartCharacters},
+		{"用户@example.com", SyntaxModeStrict, domain.SyntaxLocalPartCharacters},
+		{"\xff@example.com", SyntaxModeStandard, domain.SyntaxLocalPartCharacters},
+		{`"abc@example.com`, SyntaxModeStandard, domain.SyntaxUnterminatedQuote},
+		{`"john doe"@example.com`, SyntaxModeStrict, domain.SyntaxQuotedNotAllowed},
+		{"john(work)@example.com", SyntaxModeStandard, domain.SyntaxComment},
+		{"john (work@example.com", SyntaxModeLenient, domain.SyntaxComment},
+		{"user@", SyntaxModeStandard, domain.SyntaxEmptyDomain},
+		{"user@exa mple.com", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@example.com.", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@example.123", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@xn--zz.com", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@xn--zz.com", SyntaxModeStrict, domain.SyntaxInvalidDomain},
+		{"user@-a.com", SyntaxModeStrict, domain.SyntaxInvalidDomain},
+		{"user@-a.com", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@a-.com", SyntaxModeStrict, domain.SyntaxInvalidDomain},
+		{"user@a-.com", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@-bad-.com", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@mail.bad-.com", SyntaxModeStandard, domain.SyntaxInvalidDomain},
+		{"user@ab--cd-.com", SyntaxModeStrict, domain.SyntaxInvalidDomain},
+		{"user@localhost", SyntaxModeStandard, domain.SyntaxDomainNotQualified},
+		{"user@[192.0.2.1]", SyntaxModeStrict, domain.SyntaxIPLiteralNotAllowed},
+		{"user@[300.0.2.1]", SyntaxModeStandard, domain.SyntaxInvalidIPLiteral},
+		{"user@[IPv6:192.0.2.1]", SyntaxModeStandard, domain.SyntaxInvalidIPLiteral},
+		{"user@[192.0.2.1", SyntaxModeStandard, domain.SyntaxInvalidIPLiteral},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.want, func(t *testing.T) {
+			_, err := ParseAddress(tt.email, tt.mode)
+			var syntaxErr *domain.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("ParseAddress(%q) error = %v, want a *domain.SyntaxError", tt.email, err)
+			}
+			if syntaxErr.Reason != tt.want {
+				t.Errorf("ParseAddress(%q) reason = %q, want %q", tt.email, syntaxErr.Reason, tt.want)
+			}
+			if !errors.Is(err, domain.ErrInvalidEmail) {
+				t.Errorf("ParseAddress(%q) error does not match domain.ErrInvalidEmail", tt.email)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// Rejection categories a hook selects, e.g. with
+// "/v1/validate/email?reject=disposable,free_mail". Disposable addresses
+// are always rejected.
+const (
+	RejectDisposable       = "disposable"
+	RejectFreeMail         = "free_mail"
+	RejectRoleAccount      = domain.LocalPartRoleAccount
+	RejectRandomLocalPart  = domain.LocalPartRandom
+	RejectLocalPartPattern = domain.LocalPartPattern
+)
+
+// Decision outcomes other than the selected rejection categories
+const (
+	DecisionAllowed       = "allowed"
+	DecisionFailOpen      = "fail_open"
+	DecisionDisposable    = RejectDisposable
+	DecisionUndeliverable = "undeliverable"
+	DecisionFlagged       = "flagged"
+	DecisionRisky         = "risky"
+)
+
+// optionalRejections are the categories beyond disposable, in the order
+// they are reported when several apply
+var optionalRejections = []string{
+	RejectFreeMail,
+	RejectRoleAccount,
+	RejectLocalPartPattern,
+	RejectRandomLocalPart,
+}
+
+// Rejections holds the categories selected for a check
+type Rejections map[string]bool
+
+// ParseRejections reads rejection categories, each value holding one or more
+// comma-separated categories. Unknown categories are errors.
+func ParseRejections(values []string) (Rejections, error) {
+	set := Rejections{RejectDisposable: true}
+	for _, value := range values {
+		for _, category := range strings.Split(value, ",") {
+			category = strings.TrimSpace(category)
+			switch category {
+			case "":
+			case RejectDisposable, RejectFreeMail, RejectRoleAccount, RejectRandomLocalPart, RejectLocalPartPattern:
+				set[category] = true
+			default:
+				return nil, fmt.Errorf("unknown reject category %q", category)
+			}
+		}
+	}
+	return set, nil
+}
+
+// match returns the first selected category beyond disposable that applies
+// to a verdict. Disposable verdicts are reported as such, and allow entries
+// exempt a domain from every category.
+func (set Rejections) match(v domain.Verdict) (string, bool) {
+	if v.Disposable || v.Source == domain.SourceAllowlist || v.Source == domain.SourceCustomAllow {
+		return "", false
+	}
+	for _, category := range optionalRejections {
+		if set[category] && RejectionDetail(v, category) != "" {
+			return category, true
+		}
+	}
+	return "", false
+}
+
+// RejectionDetail returns what matched for a category, empty if nothing
+func RejectionDetail(v domain.Verdict, category string) string {
+	switch category {
+	case RejectFreeMail:
+		return v.FreeMail
+	case RejectRoleAccount:
+		return v.RoleAccount
+	case RejectRandomLocalPart:
+		return v.RandomLocalPart
+	case RejectLocalPartPattern:
+		return v.LocalPartPattern
+	}
+	return ""
+}
+
+// Decision is what the webhook does with a verdict
+type Decision struct {
+	// Outcome is a Decision* value, or the Reject* category that matched
+	Outcome string
+	// Reject reports whether the address is rejected
+	Reject bool
+	// Category is the selected rejection category that matched, if any
+	Category string
+	// Detail is what matched for Category
+	Detail string
+}
+
+// Decide applies the selected rejection categories and the risk decision to
+// a verdict. The webhook, the batch endpoint and the CLI all decide here:
+// selected categories first, then the risk decision when scoring is
+// enabled, then disposable, undeliverable and fail-open verdicts.
+func Decide(v domain.Verdict, reject Rejections) Decision {
+	if category, ok := reject.match(v); ok {
+		return Decision{Outcome: category, Reject: true, Category: category, Detail: RejectionDetail(v, category)}
+	}
+
+	if v.Risk != nil {
+		switch v.Risk.Decision {
+		case domain.RiskBlock:
+			outcome := DecisionRisky
+			switch {
+			case v.Disposable:
+				outcome = DecisionDisposable
+			case v.Undeliverable != "":
+				outcome = DecisionUndeliverable
+			}
+			return Decision{Outcome: outcome, Reject: true}
+		case domain.RiskFlag:
+			return Decision{Outcome: DecisionFlagged}
+		}
+		return allowed(v)
+	}
+
+	switch {
+	case v.Disposable:
+		return Decision{Outcome: DecisionDisposable, Reject: true}
+	case v.Undeliverable != "":
+		return Decision{Outcome: DecisionUndeliverable, Reject: true}
+	}
+	return allowed(v)
+}
+
+// allowed is the decision for an address that passes
+func allowed(v domain.Verdict) Decision {
+	if v.FailOpen {
+		return Decision{Outcome: DecisionFailOpen}
+	}
+	return Decision{Outcome: DecisionAllowed}
+}
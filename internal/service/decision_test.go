@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+func TestDecide(t *testing.T) {
+	all, err := ParseRejections([]string{"free_mail,role_account", " random_local_part ", "local_part_pattern"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	none, _ := ParseRejections(nil)
+
+	tests := []struct {
+		name         string
+		verdict      domain.Verdict
+		reject       Rejections
+		wantOutcome  string
+		wantReject   bool
+		wantCategory string
+		wantDetail   string
+	}{
+		{"allowed", domain.Verdict{}, all, DecisionAllowed, false, "", ""},
+		{"fail open", domain.Verdict{FailOpen: true}, all, DecisionFailOpen, false, "", ""},
+		{"disposable", domain.Verdict{Disposable: true, FreeMail: "gmail.com"}, all, DecisionDisposable, true, "", ""},
+		{"undeliverable", domain.Verdict{Undeliverable: domain.UndeliverableNoMX}, none, DecisionUndeliverable, true, "", ""},
+		{"free mail", domain.Verdict{FreeMail: "gmail.com", RoleAccount: "admin"}, all, RejectFreeMail, true, RejectFreeMail, "gmail.com"},
+		{"free mail not selected", domain.Verdict{FreeMail: "gmail.com"}, none, DecisionAllowed, false, "", ""},
+		{"role account", domain.Verdict{RoleAccount: "admin", RandomLocalPart: "random characters"}, all, RejectRoleAccount, true, RejectRoleAccount, "admin"},
+		{"pattern before random", domain.Verdict{LocalPartPattern: "test[0-9]*", RandomLocalPart: "random characters"}, all, RejectLocalPartPattern, true, RejectLocalPartPattern, "test[0-9]*"},
+		{"allow entry exempts", domain.Verdict{Source: domain.SourceAllowlist, FreeMail: "gmail.com"}, all, DecisionAllowed, false, "", ""},
+		{"custom allow exempts", domain.Verdict{Source: domain.SourceCustomAllow, RoleAccount: "admin"}, all, DecisionAllowed, false, "", ""},
+		{"risk block", domain.Verdict{Risk: &domain.Risk{Decision: domain.RiskBlock}}, none, DecisionRisky, true, "", ""},
+		{"risk block disposable", domain.Verdict{Disposable: true, Risk: &domain.Risk{Decision: domain.RiskBlock}}, none, DecisionDisposable, true, "", ""},
+		{"risk block undeliverable", domain.Verdict{Undeliverable: domain.UndeliverableNoDomain, Risk: &domain.Risk{Decision: domain.RiskBlock}}, none, DecisionUndeliverable, true, "", ""},
+		{"risk flag", domain.Verdict{Disposable: true, Risk: &domain.Risk{Decision: domain.RiskFlag}}, none, DecisionFlagged, false, "", ""},
+		{"risk allow", domain.Verdict{Disposable: true, Risk: &domain.Risk{Decision: domain.RiskAllow}}, none, DecisionAllowed, false, "", ""},
+		{"category before risk", domain.Verdict{RoleAccount: "admin", Risk: &domain.Risk{Decision: domain.RiskAllow}}, all, RejectRoleAccount, true, RejectRoleAccount, "admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Decide(tt.verdict, tt.reject)
+			want := Decision{Outcome: tt.wantOutcome, Reject: tt.wantReject, Category: tt.wantCategory, Detail: tt.wantDetail}
+			if d != want {
+				t.Errorf("Decide() = %+v, want %+v", d, want)
+			}
+		})
+	}
+}
+
+func TestParseRejections(t *testing.T) {
+	set, err := ParseRejections([]string{"free_mail, role_account", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, category := range []string{RejectDisposable, RejectFreeMail, RejectRoleAccount} {
+		if !set[category] {
+			t.Errorf("%s not selected", category)
+		}
+	}
+	if set[RejectRandomLocalPart] {
+		t.Errorf("%s selected", RejectRandomLocalPart)
+	}
+
+	if _, err := ParseRejections([]string{"free_mail,spam"}); err == nil {
+		t.Error("ParseRejections() with an unknown category = nil error")
+	}
+}
@@ -1,7 +1,6 @@
 package service
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -11,52 +10,192 @@ import (
 	"sync"
 	"time"
 
+	"golang.org/x/net/publicsuffix"
+
 	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
 )
 
+// maxListSize caps the size of a single fetched list
+const maxListSize = 64 << 20 // 64MB
+
 // DisposableEmailService manages the disposable email domain list
 type DisposableEmailService struct {
 	listURLs        []string
+	listMode        string
 	refreshInterval time.Duration
+	watchInterval   time.Duration
+	snapshotPath    string
+	failurePolicy   string
+	gracePeriod     time.Duration
+	syntaxMode      string
+	dns             DNSOptions
+	mxHosts         map[string]bool
+	mxList          *hostList
+	nsList          *hostList
+	freeMailList    *hostList
+	localParts      *LocalPartChecker
+	scorer          *scorer
+	overrides       Overrides
+	rules           *RuleStore
 	logger          *slog.Logger
 	httpClient      *http.Client
 
-	mu          sync.RWMutex
-	domains     map[string]bool
-	lastRefresh time.Time
-	isReady     bool
-	etags       map[string]string
+	// refreshMu serializes refreshes from the ticker and the file watcher
+	refreshMu sync.Mutex
+
+	mu      sync.RWMutex
+	domains map[string]string // domain -> source URL
+	// patterns holds the compiled glob and regex entries of domains
+	patterns *patternSet
+	// activeSource is the URL currently serving data in fallback mode
+	activeSource string
+	allow        map[string]string
+	deny         map[string]string
+	lastRefresh  time.Time
+	isReady      bool
+	startedAt    time.Time
+	sources      map[string]*sourceState
+	// domainsGen counts replacements of domains, which is never modified in
+	// place
+	domainsGen uint64
+
+	// sortedMu guards the sorted view of domains used for paging, which is
+	// built outside mu so sorting never blocks validations
+	sortedMu      sync.Mutex
+	sortedGen     uint64
+	sortedDomains []string
+
+	historyMu sync.Mutex
+	history   []RefreshReport
 }
 
-func NewDisposableEmailService(listURLs []string, refreshInterval time.Duration, log *slog.Logger) *DisposableEmailService {
+// Failure policies decide what happens while no list has ever loaded
+const (
+	// FailurePolicyOpen allows every address (default)
+	FailurePolicyOpen = "open"
+	// FailurePolicyClosed rejects every address
+	FailurePolicyClosed = "closed"
+	// FailurePolicyClosedAfterGrace allows addresses during the grace period
+	// after startup and rejects them afterwards
+	FailurePolicyClosedAfterGrace = "closed-after-grace"
+)
+
+// Options configures a DisposableEmailService
+type Options struct {
+	ListURLs []string
+	// ListMode is ListModeFallback (default) or ListModeUnion
+	ListMode        string
+	RefreshInterval time.Duration
+	// WatchInterval is how often local list files are checked for changes;
+	// zero disables watching
+	WatchInterval time.Duration
+	// SnapshotPath is where the last good list is persisted for warm starts;
+	// empty disables snapshots
+	SnapshotPath string
+	// FailurePolicy is one of the FailurePolicy* constants; empty means open
+	FailurePolicy string
+	// GracePeriod applies to FailurePolicyClosedAfterGrace
+	GracePeriod time.Duration
+	// SyntaxMode is one of the SyntaxMode* constants; empty means standard
+	SyntaxMode string
+	// DNS configures the optional MX and domain existence checks
+	DNS       DNSOptions
+	Overrides Overrides
+	// Rules holds runtime-managed allow/deny entries; nil means none
+	Rules *RuleStore
+	// LocalParts checks local parts for role accounts, random strings and
+	// patterns; nil uses the built-in role accounts and no patterns
+	LocalParts *LocalPartChecker
+	// FreeMailListURLs are lists of free mailbox provider domains; empty
+	// uses the built-in providers
+	FreeMailListURLs []string
+	// Scoring configures the optional risk scoring
+	Scoring ScoreOptions
+}
+
+func NewDisposableEmailService(opts Options, log *slog.Logger) *DisposableEmailService {
+	listMode := opts.ListMode
+	if listMode == "" {
+		listMode = ListModeFallback
+	}
+
+	rules := opts.Rules
+	if rules == nil {
+		rules, _ = OpenRuleStore("") // in-memory store never fails
+	}
+
+	localParts := opts.LocalParts
+	if localParts == nil {
+		localParts, _ = NewLocalPartChecker(LocalPartOptions{}) // defaults never fail
+	}
+
+	sources := make(map[string]*sourceState, len(opts.ListURLs))
+	for _, url := range opts.ListURLs {
+		sources[url] = &sourceState{}
+	}
+
 	return &DisposableEmailService{
-		listURLs:        listURLs,
-		refreshInterval: refreshInterval,
+		listURLs:        opts.ListURLs,
+		listMode:        listMode,
+		refreshInterval: opts.RefreshInterval,
+		watchInterval:   opts.WatchInterval,
+		snapshotPath:    opts.SnapshotPath,
+		failurePolicy:   opts.FailurePolicy,
+		gracePeriod:     opts.GracePeriod,
+		syntaxMode:      opts.SyntaxMode,
+		dns:             opts.DNS,
+		mxHosts:         normalizeMXHosts(opts.DNS.DisposableMXHosts),
+		mxList:          newHostList(HostListMX, opts.DNS.MXListURLs),
+		nsList:          newHostList(HostListNS, opts.DNS.NSListURLs),
+		freeMailList:    newHostList(HostListFreeMail, opts.FreeMailListURLs),
+		localParts:      localParts,
+		scorer:          newScorer(opts.Scoring),
+		overrides:       opts.Overrides,
+		rules:           rules,
 		logger:          log,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		domains: make(map[string]bool),
-		etags:   make(map[string]string),
+		domains: make(map[string]string),
+		allow:   make(map[string]string),
+		deny:    make(map[string]string),
+		sources: sources,
 	}
 }
 
 // Start initializes the service and starts the auto-refresh goroutine
+// A snapshot from a previous run is loaded first, so a restart during an
+// outage keeps protecting with the last good list
 // The service always starts even if initial load fails (fail mode)
 func (s *DisposableEmailService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	s.startedAt = time.Now()
+	s.mu.Unlock()
+
+	if err := s.loadSnapshot(); err != nil {
+		s.logger.Warn("failed to load snapshot - ignoring",
+			slog.String("path", s.snapshotPath),
+			slog.Any("error", err))
+	}
+
 	// Try initial load
-	if err := s.refresh(); err != nil {
-		// Always allow service to start in degraded mode
-		s.logger.Warn("failed initial load - starting in FAIL mode (allowing all)",
-			slog.Any("error", err),
-			slog.Int("urls_tried", len(s.listURLs)))
-		// isReady stays false, but service still starts
-		go s.autoRefresh(ctx)
-		return nil
+	if _, err := s.refresh(TriggerStartup); err != nil {
+		if s.IsReady() {
+			s.logger.Warn("failed initial load - serving snapshot data",
+				slog.Any("error", err),
+				slog.Int("urls_tried", len(s.listURLs)))
+		} else {
+			// Always allow service to start in degraded mode
+			// isReady stays false, but service still starts
+			s.logger.Warn("failed initial load - starting in FAIL mode (allowing all)",
+				slog.Any("error", err),
+				slog.Int("urls_tried", len(s.listURLs)))
+		}
 	}
 
-	// Success - start background refresh
+	// Start background refresh (also after a failed initial load, to recover)
 	go s.autoRefresh(ctx)
+	go s.watchFiles(ctx)
 	return nil
 }
 
@@ -71,18 +210,60 @@ func (s *DisposableEmailService) autoRefresh(ctx context.Context) {
 			s.logger.Info("stopping auto-refresh goroutine")
 			return
 		case <-ticker.C:
-			if err := s.refresh(); err != nil {
+			if _, err := s.refresh(TriggerInterval); err != nil {
 				s.logger.Error("failed to refresh disposable domains", slog.Any("error", err))
 			}
 		}
 	}
 }
 
-// refresh fetches and updates the disposable domains list
-// Tries all URLs in sequence until one succeeds
+// refresh fetches and updates the disposable domains list and records a
+// report of the per-source outcomes in the refresh history
+// Local allow/deny overrides and the host lists are reloaded on every refresh
+func (s *DisposableEmailService) refresh(trigger string) (RefreshReport, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	report := RefreshReport{
+		Trigger:   trigger,
+		Mode:      s.listMode,
+		StartedAt: time.Now(),
+	}
+
+	s.refreshOverrides()
+
+	var err error
+	if s.listMode == ListModeUnion {
+		err = s.refreshUnion(&report)
+	} else {
+		err = s.refreshFallback(&report)
+	}
+	s.refreshHostLists(&report)
+
+	report.DurationMS = time.Since(report.StartedAt).Milliseconds()
+	report.DomainCount = s.DomainCount()
+	report.Success = err == nil
+	if err != nil {
+		report.Error = err.Error()
+	}
+	s.recordHistory(report)
+
+	if err != nil {
+		return report, err
+	}
+
+	if err := s.saveSnapshot(); err != nil {
+		s.logger.Error("failed to save snapshot",
+			slog.String("path", s.snapshotPath),
+			slog.Any("error", err))
+	}
+	return report, nil
+}
+
+// refreshFallback tries all URLs in sequence until one succeeds
 // On failure with existing data: keeps old data
 // On failure without data: logs error for fail mode
-func (s *DisposableEmailService) refresh() error {
+func (s *DisposableEmailService) refreshFallback(report *RefreshReport) error {
 	s.logger.Info("refreshing disposable domains list",
 		slog.Int("urls", len(s.listURLs)))
 
@@ -95,9 +276,16 @@ func (s *DisposableEmailService) refresh() error {
 			slog.Int("attempt", i+1),
 			slog.Int("total", len(s.listURLs)))
 
-		domains, newETag, status, err := s.fetchFromURL(url)
+		s.mu.RLock()
+		etag := s.sources[url].etag
+		s.mu.RUnlock()
+
+		start := time.Now()
+		domains, newETag, status, err := s.fetchSource(url, etag, normalizeListEntry)
+		report.addOutcome(url, fetchResult{domains: domains, status: status, err: err}, time.Since(start))
 		if err != nil {
 			lastErr = err
+			s.recordFailure(url, err)
 			s.logger.Warn("failed to fetch from URL, trying next",
 				slog.String("url", url),
 				slog.Any("error", err))
@@ -105,17 +293,36 @@ func (s *DisposableEmailService) refresh() error {
 		}
 
 		if status == http.StatusNotModified {
-			// Data not modified at this source
-			s.mu.Lock()
-			if s.isReady {
-				// Consider refresh successful; update lastRefresh timestamp
-				s.lastRefresh = time.Now()
+			// Data not modified at this source - reuse its last good copy.
+			// Refreshes are serialized, so the copy cannot change until the
+			// lock is taken again.
+			s.mu.RLock()
+			st := s.sources[url]
+			cached, active := st.domains, s.activeSource == url
+			s.mu.RUnlock()
+			if cached != nil {
+				var (
+					loaded   map[string]string
+					patterns *patternSet
+				)
+				if !active {
+					loaded = withOrigin(cached, url)
+					patterns = newPatternSet(loaded)
+				}
+				s.mu.Lock()
+				st.lastSuccess = time.Now()
+				if !active {
+					s.setDomainsLocked(loaded, patterns)
+					s.activeSource = url
+				}
+				s.lastRefresh = st.lastSuccess
+				s.isReady = true
 				s.mu.Unlock()
 				s.logger.Info("disposable domains list not modified",
 					slog.String("source_url", url))
+				report.addSkipped(s.listURLs[i+1:])
 				return nil
 			}
-			s.mu.Unlock()
 			// No data yet, try next URL that may have data
 			s.logger.Warn("received 304 Not Modified but service has no data yet, trying next URL",
 				slog.String("url", url))
@@ -123,13 +330,14 @@ func (s *DisposableEmailService) refresh() error {
 		}
 
 		// SUCCESS - Update cache atomically
+		loaded := withOrigin(domains, url)
+		patterns := newPatternSet(loaded)
 		s.mu.Lock()
-		s.domains = domains
+		s.recordSuccessLocked(url, domains, newETag)
+		s.setDomainsLocked(loaded, patterns)
+		s.activeSource = url
 		s.lastRefresh = time.Now()
 		s.isReady = true
-		if newETag != "" {
-			s.etags[url] = newETag
-		}
 		s.mu.Unlock()
 
 		s.logger.Info("disposable domains list refreshed successfully",
@@ -137,6 +345,7 @@ func (s *DisposableEmailService) refresh() error {
 			slog.Int("domains_count", len(domains)),
 			slog.Time("last_refresh", s.lastRefresh))
 
+		report.addSkipped(s.listURLs[i+1:])
 		return nil
 	}
 
@@ -145,25 +354,24 @@ func (s *DisposableEmailService) refresh() error {
 	return fmt.Errorf("all %d URLs failed, last error: %w", len(s.listURLs), lastErr)
 }
 
-// fetchFromURL attempts to fetch and parse the domain list from a single URL
-func (s *DisposableEmailService) fetchFromURL(url string) (map[string]bool, string, int, error) {
+// fetchFromURL attempts to fetch and parse the list from a single URL.
+// The URL may carry an explicit "<format>+" prefix (see splitFormat).
+func fetchFromURL(client *http.Client, url, etag string, normalize entryNormalizer) (map[string]bool, string, int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	format, location := splitFormat(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
 	if err != nil {
 		return nil, "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Add conditional request header if we have an ETag
-	s.mu.RLock()
-	etag := s.etags[url]
-	s.mu.RUnlock()
 	if etag != "" {
 		req.Header.Set("If-None-Match", etag)
 	}
 
-	resp, err := s.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, "", 0, fmt.Errorf("failed to fetch: %w", err)
 	}
@@ -178,10 +386,17 @@ func (s *DisposableEmailService) fetchFromURL(url string) (map[string]bool, stri
 		return nil, "", resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	// Parse the TXT file (one domain per line)
-	domains, err := s.parseTxtFile(resp.Body)
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxListSize+1))
 	if err != nil {
-		return nil, "", resp.StatusCode, fmt.Errorf("failed to parse: %w", err)
+		return nil, "", resp.StatusCode, fmt.Errorf("failed to read body: %w", err)
+	}
+	if len(data) > maxListSize {
+		return nil, "", resp.StatusCode, fmt.Errorf("list exceeds %d bytes", maxListSize)
+	}
+
+	domains, err := parseListData(format, resp.Header.Get("Content-Type"), location, data, normalize)
+	if err != nil {
+		return nil, "", resp.StatusCode, err
 	}
 
 	// Capture ETag for future conditional requests
@@ -214,30 +429,15 @@ func (s *DisposableEmailService) handleAllRefreshFailures(lastErr error) {
 	}
 }
 
-// parseTxtFile parses a TXT file with one domain per line
-func (s *DisposableEmailService) parseTxtFile(r io.Reader) (map[string]bool, error) {
-	domains := make(map[string]bool)
-	scanner := bufio.NewScanner(r)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-
-		// Normalize to lowercase
-		domain := strings.ToLower(line)
-		domains[domain] = true
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed to scan file: %w", err)
+// parseListData parses a fetched list and rejects empty results
+func parseListData(format, contentType, location string, data []byte, normalize entryNormalizer) (map[string]bool, error) {
+	domains, err := parseList(format, contentType, location, data, normalize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
 	}
 
 	if len(domains) == 0 {
-		return nil, fmt.Errorf("no domains found in the list")
+		return nil, fmt.Errorf("no entries found in the list")
 	}
 
 	return domains, nil
@@ -245,31 +445,169 @@ func (s *DisposableEmailService) parseTxtFile(r io.Reader) (map[string]bool, err
 
 // IsDisposable checks if an email address uses a disposable domain
 func (s *DisposableEmailService) IsDisposable(email string) (bool, string, error) {
-	// Extract domain from email
-	emailDomain := extractDomain(email)
-	if emailDomain == "" {
-		return false, "", domain.ErrInvalidEmail
+	verdict, err := s.Check(email)
+	if err != nil {
+		return false, "", err
 	}
+	return verdict.Disposable, verdict.Domain, nil
+}
+
+// Check evaluates an email address against the local overrides and the
+// disposable domains list. Parent domains are matched too, so
+// "user@mx.tempmail.com" is reported as disposable when "tempmail.com" is listed.
+// Allowlist entries take precedence over the denylist and the fetched list.
+func (s *DisposableEmailService) Check(email string) (domain.Verdict, error) {
+	return s.CheckContext(context.Background(), email)
+}
+
+// auditKey marks the contexts of audit traffic
+type auditKey struct{}
+
+// WithAudit marks checks made with ctx as audit traffic, such as batch
+// backfills and offline checks: they do not count as sightings for the
+// new_domain risk signal, so they cannot crowd out live traffic, and they
+// skip the per-address warnings logged while the list is not ready.
+func WithAudit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, auditKey{}, true)
+}
+
+// isAudit reports whether ctx was marked by WithAudit
+func isAudit(ctx context.Context) bool {
+	audit, _ := ctx.Value(auditKey{}).(bool)
+	return audit
+}
+
+// CheckContext is Check with a context bounding the DNS checks. When DNS
+// checks are enabled, addresses not decided by the lists or an allow entry
+// are also checked for a disposable mail exchanger and for a domain that
+// cannot receive email (Verdict.Undeliverable). With risk scoring enabled
+// the verdict also carries the scored assessment (Verdict.Risk).
+func (s *DisposableEmailService) CheckContext(ctx context.Context, email string) (domain.Verdict, error) {
+	// Parse the address; syntax errors are *domain.SyntaxError
+	addr, err := ParseAddress(email, s.syntaxMode)
+	if err != nil {
+		return domain.Verdict{}, err
+	}
+
+	verdict, err := s.evaluate(domain.Verdict{
+		Email:         email,
+		Domain:        addr.Domain,
+		DomainUnicode: unicodeDomain(addr.Domain),
+	}, isAudit(ctx))
+	if err != nil {
+		return verdict, err
+	}
+	if !addr.IPLiteral {
+		verdict.FreeMail, verdict.FreeMailOrigin, _ = s.matchFreeMail(addr.Domain)
+	}
+	s.localParts.check(addr.LocalPart, &verdict)
+	allowed := verdict.Source == domain.SourceAllowlist || verdict.Source == domain.SourceCustomAllow
+	if !verdict.Disposable && !allowed && !addr.IPLiteral && s.dns.Resolver != nil {
+		verdict = s.checkDNS(ctx, verdict)
+	}
+	verdict.Risk = s.scorer.score(addr, verdict, isAudit(ctx))
+	return verdict, nil
+}
+
+// evaluate matches verdict.Domain against the overrides and the loaded list.
+// Audit checks skip the per-address warnings while the list is not ready.
+func (s *DisposableEmailService) evaluate(verdict domain.Verdict, audit bool) (domain.Verdict, error) {
+	email, emailDomain := verdict.Email, verdict.Domain
+	candidates := domainCandidates(emailDomain)
 
-	// Check if the service is ready
 	s.mu.RLock()
-	ready := s.isReady
-	s.mu.RUnlock()
+	defer s.mu.RUnlock()
+
+	// Custom rules and local overrides apply even before the list has loaded.
+	// Runtime rules win over static overrides, so a custom deny rule blocks a
+	// domain even when a static allow entry covers it; within each, allow
+	// entries win over deny entries.
+	if matched, rule, ok := s.rules.match(candidates, RuleAllow); ok {
+		verdict.MatchedDomain = matched
+		verdict.Source = domain.SourceCustomAllow
+		verdict.Origin = rule.Author
+		return verdict, nil
+	}
+	if matched, rule, ok := s.rules.match(candidates, RuleDeny); ok {
+		verdict.Disposable = true
+		verdict.MatchedDomain = matched
+		verdict.Source = domain.SourceCustomDeny
+		verdict.Origin = rule.Author
+		return verdict, nil
+	}
+	if matched, origin, ok := lookupOverride(s.allow, candidates); ok {
+		verdict.MatchedDomain = matched
+		verdict.Source = domain.SourceAllowlist
+		verdict.Origin = origin
+		return verdict, nil
+	}
+	if matched, origin, ok := lookupOverride(s.deny, candidates); ok {
+		verdict.Disposable = true
+		verdict.MatchedDomain = matched
+		verdict.Source = domain.SourceDenylist
+		verdict.Origin = origin
+		return verdict, nil
+	}
+
+	if !s.isReady {
+		if s.failClosedLocked() {
+			// Never successfully loaded data - reject per failure policy
+			if !audit {
+				s.logger.Warn("service not ready - rejecting request (fail closed)",
+					slog.String("email", email),
+					slog.String("domain", emailDomain),
+					slog.String("failure_policy", s.failurePolicy))
+			}
+			return verdict, domain.ErrListUnavailable
+		}
 
-	if !ready {
 		// Never successfully loaded data - always fail (allow request)
-		s.logger.Warn("service not ready - allowing request (fail mode)",
-			slog.String("email", email),
-			slog.String("domain", emailDomain))
-		return false, emailDomain, nil // false = not disposable = ALLOW
+		if !audit {
+			s.logger.Warn("service not ready - allowing request (fail mode)",
+				slog.String("email", email),
+				slog.String("domain", emailDomain))
+		}
+		verdict.FailOpen = true
+		return verdict, nil // not disposable = ALLOW
 	}
 
 	// Normal operation with data (might be old, but that's OK)
-	s.mu.RLock()
-	isDisposable := s.domains[emailDomain]
-	s.mu.RUnlock()
+	for _, candidate := range candidates {
+		if origin, ok := s.domains[candidate]; ok {
+			verdict.Disposable = true
+			verdict.MatchedDomain = candidate
+			verdict.Source = domain.SourceList
+			verdict.Origin = origin
+			return verdict, nil
+		}
+	}
+	if matched, rule, ok := s.patterns.match(candidates); ok {
+		verdict.Disposable = true
+		verdict.MatchedDomain = matched
+		verdict.MatchedRule = rule.pattern
+		verdict.Source = domain.SourceList
+		verdict.Origin = rule.origin
+	}
+
+	return verdict, nil
+}
 
-	return isDisposable, emailDomain, nil
+// Rules returns the store of runtime-managed allow/deny entries
+func (s *DisposableEmailService) Rules() *RuleStore {
+	return s.rules
+}
+
+// failClosedLocked reports whether requests must be rejected while no list
+// has loaded. Caller must hold s.mu.
+func (s *DisposableEmailService) failClosedLocked() bool {
+	switch s.failurePolicy {
+	case FailurePolicyClosed:
+		return true
+	case FailurePolicyClosedAfterGrace:
+		return !s.startedAt.IsZero() && time.Since(s.startedAt) > s.gracePeriod
+	default:
+		return false
+	}
 }
 
 // IsReady returns whether the service is ready to handle requests
@@ -279,20 +617,51 @@ func (s *DisposableEmailService) IsReady() bool {
 	return s.isReady
 }
 
-// extractDomain extracts the domain part from an email address
-func extractDomain(email string) string {
-	email = strings.TrimSpace(strings.ToLower(email))
+// DomainCount returns the number of domains in the loaded list
+func (s *DisposableEmailService) DomainCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.domains)
+}
+
+// ListAge returns the time since the last successful refresh, or since
+// startup when no list has loaded yet
+func (s *DisposableEmailService) ListAge() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.lastRefresh.IsZero() {
+		if s.startedAt.IsZero() {
+			return 0
+		}
+		return time.Since(s.startedAt)
+	}
+	return time.Since(s.lastRefresh)
+}
 
-	// Simple email validation
-	parts := strings.Split(email, "@")
-	if len(parts) != 2 {
-		return ""
+// domainCandidates returns the domain followed by each of its parent domains,
+// stopping at the registrable domain (public suffix plus one label).
+// "a.b.tempmail.com" yields "a.b.tempmail.com", "b.tempmail.com", "tempmail.com".
+func domainCandidates(d string) []string {
+	candidates := []string{d}
+	if strings.HasPrefix(d, "[") {
+		// Address literals have no parents
+		return candidates
 	}
 
-	domain := parts[1]
-	if domain == "" {
-		return ""
+	root, err := publicsuffix.EffectiveTLDPlusOne(d)
+	if err != nil || root == d || !strings.HasSuffix(d, "."+root) {
+		// Public suffix itself or unparseable - only the exact domain applies
+		return candidates
+	}
+
+	for rest := d; rest != root; {
+		i := strings.IndexByte(rest, '.')
+		if i < 0 {
+			break
+		}
+		rest = rest[i+1:]
+		candidates = append(candidates, rest)
 	}
 
-	return domain
+	return candidates
 }
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// writeList writes a list file into a test directory and returns its path
+func writeList(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newTestService creates a service with opts and loads its lists
+func newTestService(t *testing.T, opts Options) *DisposableEmailService {
+	t.Helper()
+	s := NewDisposableEmailService(opts, slog.New(slog.DiscardHandler))
+	if len(opts.ListURLs) > 0 {
+		if _, err := s.Refresh(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestDomainCandidates(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   []string
+	}{
+		{"tempmail.com", []string{"tempmail.com"}},
+		{"mx.tempmail.com", []string{"mx.tempmail.com", "tempmail.com"}},
+		{"a.b.tempmail.com", []string{"a.b.tempmail.com", "b.tempmail.com", "tempmail.com"}},
+		{"mail.tempmail.co.uk", []string{"mail.tempmail.co.uk", "tempmail.co.uk"}},
+		// Public suffixes never become candidates
+		{"co.uk", []string{"co.uk"}},
+		{"user.github.io", []string{"user.github.io"}},
+		{"a.user.github.io", []string{"a.user.github.io", "user.github.io"}},
+		{"[192.0.2.1]", []string{"[192.0.2.1]"}},
+		{"localhost", []string{"localhost"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := domainCandidates(tt.domain); !slices.Equal(got, tt.want) {
+				t.Errorf("domainCandidates(%q) = %q, want %q", tt.domain, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMatchesParentDomains(t *testing.T) {
+	list := writeList(t, "list.txt", "tempmail.com\nmail.example.org\n")
+	s := newTestService(t, Options{ListURLs: []string{list}})
+
+	tests := []struct {
+		email       string
+		wantMatched string
+	}{
+		{"user@tempmail.com", "tempmail.com"},
+		{"user@mx.tempmail.com", "tempmail.com"},
+		{"user@a.b.TempMail.com", "tempmail.com"},
+		{"user@mail.example.org", "mail.example.org"},
+		{"user@x.mail.example.org", "mail.example.org"},
+		{"user@example.org", ""},
+		{"user@nottempmail.com", ""},
+		{"user@tempmail.com.au", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			v, err := s.Check(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Disposable != (tt.wantMatched != "") || v.MatchedDomain != tt.wantMatched {
+				t.Errorf("Check(%q) = disposable %v, matched %q; want matched %q",
+					tt.email, v.Disposable, v.MatchedDomain, tt.wantMatched)
+			}
+			if v.Disposable && v.Source != domain.SourceList {
+				t.Errorf("Source = %q, want %q", v.Source, domain.SourceList)
+			}
+		})
+	}
+}
+
+func TestFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		startedAgo   time.Duration
+		wantErr      error
+		wantFailOpen bool
+	}{
+		{"default", "", 0, nil, true},
+		{"open", FailurePolicyOpen, 0, nil, true},
+		{"closed", FailurePolicyClosed, 0, domain.ErrListUnavailable, false},
+		{"within grace", FailurePolicyClosedAfterGrace, time.Minute, nil, true},
+		{"after grace", FailurePolicyClosedAfterGrace, time.Hour, domain.ErrListUnavailable, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewDisposableEmailService(Options{
+				ListURLs:      []string{"/nonexistent"},
+				FailurePolicy: tt.policy,
+				GracePeriod:   30 * time.Minute,
+				Overrides:     Overrides{DenyDomains: []string{"denied.com"}},
+			}, slog.New(slog.DiscardHandler))
+			s.startedAt = time.Now().Add(-tt.startedAgo)
+			if _, err := s.Refresh(); err == nil {
+				t.Fatal("Refresh() of a missing file = nil error")
+			}
+
+			v, err := s.Check("a@example.com")
+			if !errors.Is(err, tt.wantErr) || v.FailOpen != tt.wantFailOpen {
+				t.Errorf("Check() = fail open %v, %v; want %v, %v", v.FailOpen, err, tt.wantFailOpen, tt.wantErr)
+			}
+
+			// Local overrides still apply while the list is unavailable
+			if v, err := s.Check("a@denied.com"); err != nil || !v.Disposable || v.Source != domain.SourceDenylist {
+				t.Errorf("Check(denied) = %+v, %v; want a denylist hit", v, err)
+			}
+		})
+	}
+}
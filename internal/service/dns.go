@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net/netip"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/resolver"
+)
+
+// mxHostsOrigin names the configured disposable mail exchangers in verdicts
+const mxHostsOrigin = "DNS_DISPOSABLE_MX_HOSTS"
+
+// DNSOptions configures the optional DNS checks
+type DNSOptions struct {
+	// Resolver performs the lookups; nil disables the DNS checks
+	Resolver resolver.Resolver
+	// RequireMX rejects domains without MX records instead of falling back
+	// to their address records (the implicit MX of RFC 5321 section 5.1)
+	RequireMX bool
+	// DisposableMXHosts are mail exchangers known to serve disposable
+	// domains; subdomains match too, so "mailinator.com" covers
+	// "mail.mailinator.com"
+	DisposableMXHosts []string
+	// MXListURLs are list sources of mail exchangers known to serve
+	// disposable domains: host names, IP addresses or CIDR ranges
+	MXListURLs []string
+	// NSListURLs are list sources of nameservers known to serve disposable
+	// domains, in the same format as MXListURLs
+	NSListURLs []string
+}
+
+// normalizeMXHosts converts the configured mail exchangers to a lookup set
+func normalizeMXHosts(hosts []string) map[string]bool {
+	set := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if h, ok := normalizeEntry(host); ok {
+			set[h] = true
+		}
+	}
+	return set
+}
+
+// maxResolvedHosts caps how many mail exchangers or nameservers of a domain
+// are resolved to addresses for matching against listed IP ranges
+const maxResolvedHosts = 3
+
+// checkDNS looks up the mail exchangers of verdict.Domain. It marks the
+// verdict disposable when an exchanger or nameserver is a known disposable
+// one, and undeliverable when the domain cannot receive email. Lookup
+// failures other than "not found" (timeouts, server errors) leave the
+// verdict unchanged.
+func (s *DisposableEmailService) checkDNS(ctx context.Context, verdict domain.Verdict) domain.Verdict {
+	log := s.logger.With(slog.String("domain", verdict.Domain))
+
+	mx, err := s.dns.Resolver.LookupMX(ctx, verdict.Domain)
+	if err != nil && !resolver.IsNotFound(err) {
+		metrics.DNSLookups.WithLabelValues("mx", metrics.DNSError).Inc()
+		log.Warn("MX lookup failed - skipping DNS checks", slog.Any("error", err))
+		return verdict
+	}
+
+	if len(mx) > 0 {
+		metrics.DNSLookups.WithLabelValues("mx", metrics.DNSFound).Inc()
+		if len(mx) == 1 && (mx[0].Host == "." || mx[0].Host == "") {
+			verdict.NoMX = true
+			verdict.Undeliverable = domain.UndeliverableNullMX
+			return verdict
+		}
+		names := make([]string, 0, len(mx))
+		for _, record := range mx {
+			if host, ok := normalizeEntry(record.Host); ok {
+				names = append(names, host)
+			}
+		}
+		if match, ok := s.matchMXHost(names); ok {
+			return markHostMatch(verdict, domain.SourceMX, match)
+		}
+		if match, ok := s.matchHostList(ctx, s.mxList, names); ok {
+			return markHostMatch(verdict, domain.SourceMX, match)
+		}
+		return s.checkNS(ctx, verdict)
+	}
+	metrics.DNSLookups.WithLabelValues("mx", metrics.DNSNotFound).Inc()
+	verdict.NoMX = true
+	// An empty answer (NODATA) means the domain exists
+	exists := resolver.IsNoData(err)
+
+	// No MX record: RFC 5321 falls back to the address records
+	hosts, err := s.dns.Resolver.LookupHost(ctx, verdict.Domain)
+	switch {
+	case err != nil && !resolver.IsNotFound(err):
+		metrics.DNSLookups.WithLabelValues("host", metrics.DNSError).Inc()
+		log.Warn("address lookup failed - skipping DNS checks", slog.Any("error", err))
+	case len(hosts) == 0 && (exists || resolver.IsNoData(err)):
+		metrics.DNSLookups.WithLabelValues("host", metrics.DNSNotFound).Inc()
+		verdict.Undeliverable = domain.UndeliverableNoMX
+	case len(hosts) == 0:
+		metrics.DNSLookups.WithLabelValues("host", metrics.DNSNotFound).Inc()
+		verdict.Undeliverable = domain.UndeliverableNoDomain
+	default:
+		metrics.DNSLookups.WithLabelValues("host", metrics.DNSFound).Inc()
+		// The domain itself is the implicit mail exchanger
+		if match, ok := s.matchAddrs(s.mxList, verdict.Domain, hosts); ok {
+			return markHostMatch(verdict, domain.SourceMX, match)
+		}
+		if verdict = s.checkNS(ctx, verdict); verdict.Disposable {
+			return verdict
+		}
+		if s.dns.RequireMX {
+			verdict.Undeliverable = domain.UndeliverableNoMX
+		}
+	}
+	return verdict
+}
+
+// checkNS marks the verdict disposable when a nameserver of the registrable
+// domain of verdict.Domain is on the nameserver list
+func (s *DisposableEmailService) checkNS(ctx context.Context, verdict domain.Verdict) domain.Verdict {
+	if len(s.nsList.urls) == 0 {
+		return verdict
+	}
+
+	// Subdomains rarely have NS records of their own
+	zone, err := publicsuffix.EffectiveTLDPlusOne(verdict.Domain)
+	if err != nil {
+		zone = verdict.Domain
+	}
+
+	ns, err := s.dns.Resolver.LookupNS(ctx, zone)
+	switch {
+	case err != nil && !resolver.IsNotFound(err):
+		metrics.DNSLookups.WithLabelValues("ns", metrics.DNSError).Inc()
+		s.logger.Warn("NS lookup failed - skipping nameserver check",
+			slog.String("domain", zone),
+			slog.Any("error", err))
+		return verdict
+	case len(ns) == 0:
+		metrics.DNSLookups.WithLabelValues("ns", metrics.DNSNotFound).Inc()
+		return verdict
+	}
+	metrics.DNSLookups.WithLabelValues("ns", metrics.DNSFound).Inc()
+
+	names := make([]string, 0, len(ns))
+	for _, record := range ns {
+		if host, ok := normalizeEntry(record.Host); ok {
+			names = append(names, host)
+		}
+	}
+	if match, ok := s.matchHostList(ctx, s.nsList, names); ok {
+		return markHostMatch(verdict, domain.SourceNS, match)
+	}
+	return verdict
+}
+
+// markHostMatch marks the verdict disposable because of a matched mail
+// exchanger (source domain.SourceMX) or nameserver (domain.SourceNS)
+func markHostMatch(verdict domain.Verdict, source string, match hostMatch) domain.Verdict {
+	verdict.Disposable = true
+	verdict.FailOpen = false
+	verdict.Source = source
+	verdict.MatchedDomain = match.entry
+	verdict.Origin = match.origin
+	verdict.MatchedIP = match.ip
+	if source == domain.SourceNS {
+		verdict.NSHost = match.host
+	} else {
+		verdict.MXHost = match.host
+	}
+	return verdict
+}
+
+// matchMXHost returns the first mail exchanger that is, or is a subdomain
+// of, a mail exchanger configured in DNSOptions.DisposableMXHosts
+func (s *DisposableEmailService) matchMXHost(names []string) (hostMatch, bool) {
+	for _, host := range names {
+		for candidate := host; ; {
+			if s.mxHosts[candidate] {
+				return hostMatch{host: host, entry: candidate, origin: mxHostsOrigin}, true
+			}
+			i := strings.IndexByte(candidate, '.')
+			if i < 0 || !strings.Contains(candidate[i+1:], ".") {
+				break
+			}
+			candidate = candidate[i+1:]
+		}
+	}
+	return hostMatch{}, false
+}
+
+// matchHostList matches host names against list: by name first, then, when
+// the list has IP ranges, by the addresses of the first few hosts
+func (s *DisposableEmailService) matchHostList(ctx context.Context, list *hostList, names []string) (hostMatch, bool) {
+	s.mu.RLock()
+	for _, host := range names {
+		if match, ok := list.matchNameLocked(host); ok {
+			s.mu.RUnlock()
+			return match, true
+		}
+	}
+	hasRanges := len(list.ranges) > 0
+	s.mu.RUnlock()
+
+	if !hasRanges {
+		return hostMatch{}, false
+	}
+	for _, host := range names[:min(len(names), maxResolvedHosts)] {
+		addrs, err := s.dns.Resolver.LookupHost(ctx, host)
+		switch {
+		case err != nil && !resolver.IsNotFound(err):
+			metrics.DNSLookups.WithLabelValues("host", metrics.DNSError).Inc()
+			s.logger.Debug("address lookup failed",
+				slog.String("host", host),
+				slog.Any("error", err))
+			continue
+		case len(addrs) == 0:
+			metrics.DNSLookups.WithLabelValues("host", metrics.DNSNotFound).Inc()
+			continue
+		}
+		metrics.DNSLookups.WithLabelValues("host", metrics.DNSFound).Inc()
+		if match, ok := s.matchAddrs(list, host, addrs); ok {
+			return match, true
+		}
+	}
+	return hostMatch{}, false
+}
+
+// matchAddrs returns the first address of host within a range of list
+func (s *DisposableEmailService) matchAddrs(list *hostList, host string, addrs []string) (hostMatch, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range addrs {
+		addr, err := netip.ParseAddr(a)
+		if err != nil {
+			continue
+		}
+		if match, ok := list.matchAddrLocked(host, addr); ok {
+			return match, true
+		}
+	}
+	return hostMatch{}, false
+}
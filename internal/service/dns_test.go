@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/resolver"
+)
+
+func TestCheckDNS(t *testing.T) {
+	f := resolver.NewFake()
+	f.MX["mail.test"] = []*net.MX{{Host: "mx.mail.test.", Pref: 10}}
+	f.MX["nullmx.test"] = []*net.MX{{Host: ".", Pref: 0}}
+	f.MX["temp.test"] = []*net.MX{{Host: "in.mailinator.com.", Pref: 10}}
+	// No MX record, but address records: the implicit MX
+	f.Hosts["implicit.test"] = []string{"192.0.2.1"}
+	// The name exists without MX or address records (NODATA)
+	f.NS["nodata.test"] = []*net.NS{{Host: "ns1.example.net."}}
+	f.Errors["broken.test"] = &net.DNSError{Err: "server misbehaving", Name: "broken.test", IsTemporary: true}
+
+	tests := []struct {
+		domain            string
+		requireMX         bool
+		wantUndeliverable string
+		wantNoMX          bool
+		wantDisposable    bool
+	}{
+		{"mail.test", false, "", false, false},
+		{"nullmx.test", false, domain.UndeliverableNullMX, true, false},
+		{"missing.test", false, domain.UndeliverableNoDomain, true, false},
+		{"nodata.test", false, domain.UndeliverableNoMX, true, false},
+		{"implicit.test", false, "", true, false},
+		{"implicit.test", true, domain.UndeliverableNoMX, true, false},
+		{"temp.test", false, "", false, true},
+		// Lookup failures leave the verdict unchanged
+		{"broken.test", false, "", false, false},
+	}
+	for _, tt := range tests {
+		name := tt.domain
+		if tt.requireMX {
+			name += "/require_mx"
+		}
+		t.Run(name, func(t *testing.T) {
+			s := NewDisposableEmailService(Options{DNS: DNSOptions{
+				Resolver:          f,
+				RequireMX:         tt.requireMX,
+				DisposableMXHosts: []string{"mailinator.com"},
+			}}, slog.New(slog.DiscardHandler))
+
+			v := s.checkDNS(context.Background(), domain.Verdict{Domain: tt.domain})
+			if v.Undeliverable != tt.wantUndeliverable {
+				t.Errorf("Undeliverable = %q, want %q", v.Undeliverable, tt.wantUndeliverable)
+			}
+			if v.NoMX != tt.wantNoMX {
+				t.Errorf("NoMX = %v, want %v", v.NoMX, tt.wantNoMX)
+			}
+			if v.Disposable != tt.wantDisposable {
+				t.Errorf("Disposable = %v, want %v", v.Disposable, tt.wantDisposable)
+			}
+			if tt.wantDisposable && (v.Source != domain.SourceMX || v.MXHost != "in.mailinator.com" || v.MatchedDomain != "mailinator.com") {
+				t.Errorf("verdict = source %q, mx host %q, matched %q; want the mailinator.com exchanger",
+					v.Source, v.MXHost, v.MatchedDomain)
+			}
+		})
+	}
+}
+
+func TestCheckSkipsDNSForDecidedDomains(t *testing.T) {
+	f := resolver.NewFake()
+	list := writeList(t, "list.txt", "tempmail.com\n")
+	s := newTestService(t, Options{
+		ListURLs:  []string{list},
+		DNS:       DNSOptions{Resolver: f},
+		Overrides: Overrides{AllowDomains: []string{"partner.test"}},
+	})
+
+	for _, email := range []string{"a@tempmail.com", "a@partner.test", "a@[192.0.2.1]"} {
+		if _, err := s.Check(email); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.Lookups) != 0 {
+		t.Errorf("DNS lookups for decided domains: %v", f.Lookups)
+	}
+
+	v, err := s.Check("a@missing.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Undeliverable != domain.UndeliverableNoDomain {
+		t.Errorf("Check(a@missing.test).Undeliverable = %q, want %q", v.Undeliverable, domain.UndeliverableNoDomain)
+	}
+}
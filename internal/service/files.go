@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/metrics"
+)
+
+// localPath returns the filesystem path of a file:// URL or plain path source
+func localPath(location string) (string, bool) {
+	if strings.HasPrefix(location, "file://") {
+		u, err := url.Parse(location)
+		if err != nil {
+			return "", false
+		}
+		return u.Path, true
+	}
+	if strings.Contains(location, "://") {
+		return "", false
+	}
+	return location, true
+}
+
+// fetchSource fetches a list from an HTTP(S) URL or a local file/directory.
+// etag is the validator of the last good copy, if any; entries are converted
+// with normalize.
+func (s *DisposableEmailService) fetchSource(source, etag string, normalize entryNormalizer) (map[string]bool, string, int, error) {
+	label := s.sourceLabel(source)
+	metrics.RefreshAttempts.WithLabelValues(label).Inc()
+
+	var (
+		domains map[string]bool
+		newETag string
+		status  int
+		err     error
+	)
+	format, location := splitFormat(source)
+	if path, ok := localPath(location); ok {
+		domains, newETag, status, err = fetchFromFile(format, path, etag, normalize)
+	} else {
+		domains, newETag, status, err = fetchFromURL(s.httpClient, source, etag, normalize)
+	}
+
+	switch {
+	case err != nil:
+		metrics.RefreshFailures.WithLabelValues(label).Inc()
+	case status == http.StatusNotModified:
+		metrics.NotModified.WithLabelValues(label).Inc()
+		metrics.RefreshSuccesses.WithLabelValues(label).Inc()
+	default:
+		metrics.RefreshSuccesses.WithLabelValues(label).Inc()
+	}
+
+	return domains, newETag, status, err
+}
+
+// sourceLabel names a source in metrics by its list and position, e.g.
+// "list/0" for the first DISPOSABLE_LIST_URLS entry or "mx/1". Source URLs
+// can carry tokens or credentials and /metrics is served without
+// authentication, so they never appear in labels.
+func (s *DisposableEmailService) sourceLabel(source string) string {
+	if i := slices.Index(s.listURLs, source); i >= 0 {
+		return fmt.Sprintf("list/%d", i)
+	}
+	for _, list := range s.hostLists() {
+		if i := slices.Index(list.urls, source); i >= 0 {
+			return fmt.Sprintf("%s/%d", list.kind, i)
+		}
+	}
+	return "other"
+}
+
+// fetchFromFile loads a list from a single file or every file in a directory.
+// The modification-time fingerprint plays the role of an ETag, so an
+// unchanged file reports http.StatusNotModified like a conditional request.
+func fetchFromFile(format, path, etag string, normalize entryNormalizer) (map[string]bool, string, int, error) {
+	fingerprint, files, err := fileFingerprint(path)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	if etag != "" && etag == fingerprint {
+		return nil, "", http.StatusNotModified, nil
+	}
+
+	domains := make(map[string]bool)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		parsed, err := parseList(format, "", file, data, normalize)
+		if err != nil {
+			return nil, "", 0, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for d := range parsed {
+			domains[d] = true
+		}
+	}
+
+	if len(domains) == 0 {
+		return nil, "", 0, fmt.Errorf("no entries found in %s", path)
+	}
+
+	return domains, fingerprint, http.StatusOK, nil
+}
+
+// fileFingerprint lists the list files under path (the path itself for a
+// regular file, non-hidden regular files for a directory) and returns a
+// fingerprint of their names, sizes and modification times
+func fileFingerprint(path string) (string, []string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+
+	var files []string
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read directory %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+		sort.Strings(files)
+	} else {
+		files = []string{path}
+	}
+
+	h := fnv.New64a()
+	for _, file := range files {
+		fi, err := os.Stat(file)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to stat %s: %w", file, err)
+		}
+		fmt.Fprintf(h, "%s:%d:%d;", file, fi.Size(), fi.ModTime().UnixNano())
+	}
+
+	return fmt.Sprintf("%x", h.Sum64()), files, nil
+}
+
+// watchFiles polls local list and override files and refreshes as soon as
+// one of them changes, instead of waiting for the refresh ticker
+func (s *DisposableEmailService) watchFiles(ctx context.Context) {
+	paths := s.watchedPaths()
+	if len(paths) == 0 || s.watchInterval <= 0 {
+		return
+	}
+
+	last := make(map[string]string, len(paths))
+	for _, path := range paths {
+		last[path], _, _ = fileFingerprint(path)
+	}
+
+	ticker := time.NewTicker(s.watchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var changed []string
+			for _, path := range paths {
+				fingerprint, _, _ := fileFingerprint(path)
+				if fingerprint != last[path] {
+					last[path] = fingerprint
+					changed = append(changed, path)
+				}
+			}
+			if len(changed) == 0 {
+				continue
+			}
+
+			s.logger.Info("local list files changed - refreshing",
+				slog.Any("paths", changed))
+			if _, err := s.refresh(TriggerFileChange); err != nil {
+				s.logger.Error("failed to refresh disposable domains", slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// watchedPaths returns the local paths of list sources, host list sources
+// and override files
+func (s *DisposableEmailService) watchedPaths() []string {
+	sources := append([]string{}, s.listURLs...)
+	for _, list := range s.hostLists() {
+		sources = append(sources, list.urls...)
+	}
+
+	var paths []string
+	for _, source := range sources {
+		_, location := splitFormat(source)
+		if path, ok := localPath(location); ok {
+			paths = append(paths, path)
+		}
+	}
+	for _, file := range append(append([]string{}, s.overrides.AllowFiles...), s.overrides.DenyFiles...) {
+		_, path := splitFormat(file)
+		paths = append(paths, path)
+	}
+	return paths
+}
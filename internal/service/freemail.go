@@ -0,0 +1,39 @@
+package service
+
+// FreeMailBuiltin is the origin reported for matches against the built-in
+// free mailbox providers
+const FreeMailBuiltin = "builtin"
+
+// freeMailDomains are well-known free mailbox providers, used until a
+// free-mail list has loaded
+var freeMailDomains = map[string]bool{
+	"gmail.com": true, "googlemail.com": true,
+	"outlook.com": true, "hotmail.com": true, "live.com": true, "msn.com": true,
+	"yahoo.com": true, "ymail.com": true, "aol.com": true,
+	"icloud.com": true, "me.com": true, "mac.com": true,
+	"proton.me": true, "protonmail.com": true,
+	"gmx.de": true, "gmx.net": true, "gmx.com": true, "web.de": true, "mail.com": true,
+	"yandex.ru": true, "yandex.com": true, "mail.ru": true,
+	"zoho.com": true, "qq.com": true, "163.com": true, "126.com": true,
+}
+
+// matchFreeMail returns the free mailbox provider entry matching d or one of
+// its parent domains, and where it was listed: the list URL, or
+// FreeMailBuiltin while no free-mail list has loaded.
+func (s *DisposableEmailService) matchFreeMail(d string) (string, string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.freeMailList.hosts) > 0 {
+		if match, ok := s.freeMailList.matchNameLocked(d); ok {
+			return match.entry, match.origin, true
+		}
+		return "", "", false
+	}
+	for _, candidate := range domainCandidates(d) {
+		if freeMailDomains[candidate] {
+			return candidate, FreeMailBuiltin, true
+		}
+	}
+	return "", "", false
+}
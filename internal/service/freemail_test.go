@@ -0,0 +1,41 @@
+package service
+
+import "testing"
+
+func TestCheckFreeMail(t *testing.T) {
+	list := writeList(t, "list.txt", "tempmail.com\n")
+	freeList := writeList(t, "free.txt", "mail.example\nwebmail.test\n")
+
+	tests := []struct {
+		name       string
+		freeURLs   []string
+		email      string
+		wantEntry  string
+		wantOrigin string
+	}{
+		{"builtin", nil, "a@gmail.com", "gmail.com", FreeMailBuiltin},
+		{"builtin subdomain", nil, "a@eu.outlook.com", "outlook.com", FreeMailBuiltin},
+		{"builtin miss", nil, "a@example.com", "", ""},
+		{"list", []string{freeList}, "a@mail.example", "mail.example", freeList},
+		{"list subdomain", []string{freeList}, "a@eu.webmail.test", "webmail.test", freeList},
+		// A loaded list replaces the built-in providers
+		{"list replaces builtin", []string{freeList}, "a@gmail.com", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, Options{ListURLs: []string{list}, FreeMailListURLs: tt.freeURLs})
+			v, err := s.Check(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.FreeMail != tt.wantEntry || v.FreeMailOrigin != tt.wantOrigin {
+				t.Errorf("Check(%q) free mail = %q from %q, want %q from %q",
+					tt.email, v.FreeMail, v.FreeMailOrigin, tt.wantEntry, tt.wantOrigin)
+			}
+			// Free mail is a classification, not a disposable verdict
+			if v.Disposable {
+				t.Errorf("Check(%q) is disposable", tt.email)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Host list kinds
+const (
+	// HostListMX lists mail exchangers of disposable providers
+	HostListMX = "mx"
+	// HostListNS lists nameservers of disposable providers
+	HostListNS = "ns"
+	// HostListFreeMail lists the domains of free mailbox providers
+	HostListFreeMail = "free_mail"
+)
+
+// hostList holds host names and IP ranges of the infrastructure shared by
+// disposable providers. Providers rotate through fresh domains but keep
+// their mail servers and nameservers, so a domain missing from the domain
+// lists is still caught by where it points. Sources are always merged as in
+// union mode. The free-mail list reuses the same machinery for the domains
+// of free mailbox providers. Guarded by DisposableEmailService.mu.
+type hostList struct {
+	kind    string
+	urls    []string
+	sources map[string]*sourceState
+	// normalize validates the entries of the sources
+	normalize entryNormalizer
+	// hosts maps each listed host name to the URL it was loaded from
+	hosts map[string]string
+	// ranges are the listed IP addresses and CIDR ranges
+	ranges []hostRange
+}
+
+// hostRange is a listed IP range and the URL it was loaded from
+type hostRange struct {
+	prefix netip.Prefix
+	origin string
+}
+
+// hostMatch describes a host that matched a host list entry
+type hostMatch struct {
+	// host is the mail exchanger or nameserver of the checked domain
+	host string
+	// entry is the list entry that matched: a host name or an IP range
+	entry  string
+	origin string
+	// ip is the address of host that fell within entry, for range matches
+	ip string
+}
+
+// newHostList creates an empty host list fed by urls. The MX and NS lists
+// accept host names, IP addresses and CIDR ranges; the free-mail list only
+// domains.
+func newHostList(kind string, urls []string) *hostList {
+	normalize := normalizeHostEntry
+	if kind == HostListFreeMail {
+		normalize = normalizeEntry
+	}
+	sources := make(map[string]*sourceState, len(urls))
+	for _, url := range urls {
+		sources[url] = &sourceState{}
+	}
+	return &hostList{
+		kind:      kind,
+		urls:      urls,
+		sources:   sources,
+		normalize: normalize,
+		hosts:     make(map[string]string),
+	}
+}
+
+// hostLists returns the configured host lists
+func (s *DisposableEmailService) hostLists() []*hostList {
+	var lists []*hostList
+	for _, list := range []*hostList{s.mxList, s.nsList, s.freeMailList} {
+		if len(list.urls) > 0 {
+			lists = append(lists, list)
+		}
+	}
+	return lists
+}
+
+// normalizeHostEntry accepts a host name, an IP address or a CIDR range.
+// Addresses and ranges are returned in canonical CIDR form.
+func normalizeHostEntry(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if prefix, err := netip.ParsePrefix(raw); err == nil {
+		return prefix.Masked().String(), true
+	}
+	if addr, err := netip.ParseAddr(raw); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()).String(), true
+	}
+	return normalizeEntry(raw)
+}
+
+// rebuildLocked merges the last good copy of every source. Earlier URLs win
+// when an entry appears in several lists. Caller must hold s.mu for writing.
+func (l *hostList) rebuildLocked() {
+	hosts := make(map[string]string)
+	var ranges []hostRange
+	seen := make(map[string]bool)
+	for _, url := range l.urls {
+		for entry := range l.sources[url].domains {
+			if seen[entry] {
+				continue
+			}
+			seen[entry] = true
+			if prefix, err := netip.ParsePrefix(entry); err == nil {
+				ranges = append(ranges, hostRange{prefix: prefix, origin: url})
+			} else {
+				hosts[entry] = url
+			}
+		}
+	}
+	l.hosts = hosts
+	l.ranges = ranges
+}
+
+// matchNameLocked returns the entry matching host or one of its parent
+// domains. Caller must hold s.mu.
+func (l *hostList) matchNameLocked(host string) (hostMatch, bool) {
+	for candidate := host; ; {
+		if origin, ok := l.hosts[candidate]; ok {
+			return hostMatch{host: host, entry: candidate, origin: origin}, true
+		}
+		i := strings.IndexByte(candidate, '.')
+		if i < 0 || !strings.Contains(candidate[i+1:], ".") {
+			return hostMatch{}, false
+		}
+		candidate = candidate[i+1:]
+	}
+}
+
+// matchAddrLocked returns the range containing addr. Caller must hold s.mu.
+func (l *hostList) matchAddrLocked(host string, addr netip.Addr) (hostMatch, bool) {
+	addr = addr.Unmap()
+	for _, r := range l.ranges {
+		if r.prefix.Contains(addr) {
+			return hostMatch{host: host, entry: r.prefix.String(), origin: r.origin, ip: addr.String()}, true
+		}
+	}
+	return hostMatch{}, false
+}
+
+// refreshHostLists fetches every host list source and merges the results.
+// Host lists only add signals to the DNS checks and the free-mail check, so
+// their failures are recorded in the report but never fail the refresh.
+func (s *DisposableEmailService) refreshHostLists(report *RefreshReport) {
+	for _, list := range s.hostLists() {
+		s.refreshHostList(list, report)
+	}
+}
+
+// refreshHostList fetches the sources of one host list concurrently. A
+// failing source keeps contributing its last good copy.
+func (s *DisposableEmailService) refreshHostList(list *hostList, report *RefreshReport) {
+	results := make([]fetchResult, len(list.urls))
+	durations := make([]time.Duration, len(list.urls))
+	etags := make([]string, len(list.urls))
+	s.mu.RLock()
+	for i, url := range list.urls {
+		etags[i] = list.sources[url].etag
+	}
+	s.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for i, url := range list.urls {
+		wg.Go(func() {
+			start := time.Now()
+			entries, etag, status, err := s.fetchSource(url, etags[i], list.normalize)
+			results[i] = fetchResult{domains: entries, etag: etag, status: status, err: err}
+			durations[i] = time.Since(start)
+		})
+	}
+	wg.Wait()
+
+	for i, url := range list.urls {
+		report.addOutcome(url, results[i], durations[i])
+		report.Sources[len(report.Sources)-1].List = list.kind
+	}
+
+	s.mu.Lock()
+	for i, url := range list.urls {
+		st := list.sources[url]
+		switch res := results[i]; {
+		case res.err != nil:
+			st.lastFailure = time.Now()
+			st.lastError = res.err
+			s.logger.Warn("failed to fetch host list, keeping its previous contribution",
+				slog.String("list", list.kind),
+				slog.String("url", url),
+				slog.Any("error", res.err))
+		case res.status == http.StatusNotModified:
+			st.lastSuccess = time.Now()
+		default:
+			st.domains = res.domains
+			st.lastSuccess = time.Now()
+			st.lastError = nil
+			if res.etag != "" {
+				st.etag = res.etag
+			}
+		}
+	}
+	list.rebuildLocked()
+	hosts, ranges := len(list.hosts), len(list.ranges)
+	s.mu.Unlock()
+
+	s.logger.Info("host list refreshed",
+		slog.String("list", list.kind),
+		slog.Int("sources", len(list.urls)),
+		slog.Int("hosts_count", hosts),
+		slog.Int("ranges_count", ranges))
+}
+
+// statusLocked reports the sources of a host list. Caller must hold s.mu.
+func (l *hostList) statusLocked() []SourceStatus {
+	sources := make([]SourceStatus, 0, len(l.urls))
+	for _, url := range l.urls {
+		st := l.sources[url]
+		src := SourceStatus{
+			URL:         url,
+			List:        l.kind,
+			ETag:        st.etag,
+			DomainCount: len(st.domains),
+			LastSuccess: st.lastSuccess,
+			LastFailure: st.lastFailure,
+			Active:      st.domains != nil,
+		}
+		if st.lastError != nil {
+			src.LastError = st.lastError.Error()
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// hostListByKind returns the host list of the given kind
+func (s *DisposableEmailService) hostListByKind(kind string) (*hostList, error) {
+	switch kind {
+	case HostListMX:
+		return s.mxList, nil
+	case HostListNS:
+		return s.nsList, nil
+	case HostListFreeMail:
+		return s.freeMailList, nil
+	}
+	return nil, fmt.Errorf("unknown host list %q", kind)
+}
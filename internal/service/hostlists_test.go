@@ -0,0 +1,112 @@
+package service
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/resolver"
+)
+
+func TestNormalizeHostEntry(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"MX.Example.COM", "mx.example.com", true},
+		{"192.0.2.7", "192.0.2.7/32", true},
+		{"::ffff:192.0.2.7", "192.0.2.7/32", true},
+		{"198.51.100.77/24", "198.51.100.0/24", true},
+		{"2001:db8::1", "2001:db8::1/128", true},
+		{"2001:db8::/32", "2001:db8::/32", true},
+		{"not a host", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, ok := normalizeHostEntry(tt.raw)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("normalizeHostEntry(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCheckHostLists(t *testing.T) {
+	f := resolver.NewFake()
+	f.MX["named.test"] = []*net.MX{{Host: "mx1.trashmail-backend.net.", Pref: 10}}
+	f.MX["ranged.test"] = []*net.MX{{Host: "mx.rotating.test.", Pref: 10}}
+	f.Hosts["mx.rotating.test"] = []string{"203.0.113.25"}
+	// No MX record: the domain's own address is the implicit exchanger
+	f.Hosts["implicit.test"] = []string{"203.0.113.99"}
+	f.MX["parked.example.org"] = []*net.MX{{Host: "mx.clean.test.", Pref: 10}}
+	f.NS["example.org"] = []*net.NS{{Host: "ns2.tempdns.net."}}
+	f.MX["clean.test"] = []*net.MX{{Host: "mx.clean.test.", Pref: 10}}
+	f.Hosts["mx.clean.test"] = []string{"192.0.2.1"}
+	f.NS["clean.test"] = []*net.NS{{Host: "ns.clean.test."}}
+
+	list := writeList(t, "list.txt", "tempmail.com\n")
+	mxList := writeList(t, "mx.txt", "trashmail-backend.net\n203.0.113.0/24\n")
+	nsList := writeList(t, "ns.txt", "tempdns.net\n")
+	s := newTestService(t, Options{
+		ListURLs: []string{list},
+		DNS:      DNSOptions{Resolver: f, MXListURLs: []string{mxList}, NSListURLs: []string{nsList}},
+	})
+
+	tests := []struct {
+		email          string
+		wantDisposable bool
+		wantSource     string
+		wantHost       string
+		wantEntry      string
+		wantIP         string
+		wantOrigin     string
+	}{
+		{"a@named.test", true, domain.SourceMX, "mx1.trashmail-backend.net", "trashmail-backend.net", "", mxList},
+		{"a@ranged.test", true, domain.SourceMX, "mx.rotating.test", "203.0.113.0/24", "203.0.113.25", mxList},
+		{"a@implicit.test", true, domain.SourceMX, "implicit.test", "203.0.113.0/24", "203.0.113.99", mxList},
+		// Nameservers are looked up on the registrable domain
+		{"a@parked.example.org", true, domain.SourceNS, "ns2.tempdns.net", "tempdns.net", "", nsList},
+		{"a@clean.test", false, "", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			v, err := s.Check(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			host := v.MXHost
+			if tt.wantSource == domain.SourceNS {
+				host = v.NSHost
+			}
+			if v.Disposable != tt.wantDisposable || v.Source != tt.wantSource || host != tt.wantHost ||
+				v.MatchedDomain != tt.wantEntry || v.MatchedIP != tt.wantIP || v.Origin != tt.wantOrigin {
+				t.Errorf("Check(%q) = %v %q host %q entry %q ip %q origin %q; want %v %q host %q entry %q ip %q origin %q",
+					tt.email, v.Disposable, v.Source, host, v.MatchedDomain, v.MatchedIP, v.Origin,
+					tt.wantDisposable, tt.wantSource, tt.wantHost, tt.wantEntry, tt.wantIP, tt.wantOrigin)
+			}
+		})
+	}
+
+	// A failing host list keeps its entries and does not fail the refresh
+	if err := os.Remove(mxList); err != nil {
+		t.Fatal(err)
+	}
+	report, err := s.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh() with a missing host list = %v", err)
+	}
+	failed := false
+	for _, src := range report.Sources {
+		if src.URL == mxList && src.List == HostListMX {
+			failed = src.Error != ""
+		}
+	}
+	if !failed {
+		t.Errorf("refresh report does not record the failed host list: %+v", report.Sources)
+	}
+	if v, _ := s.Check("a@named.test"); !v.Disposable {
+		t.Error("host list entries dropped after a failed refresh")
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// errNotDomain is returned for entries that cannot be a domain name at all
+var errNotDomain = errors.New("not a domain name")
+
+// idnaProfile maps domains to their canonical ASCII form following UTS #46:
+// case and compatibility characters are folded ("ＴＥＭＰＭＡＩＬ.com" becomes
+// "tempmail.com"), Unicode labels are converted to punycode, and invalid
+// labels (bad punycode, disallowed runes, empty or over-long labels) are
+// rejected. The UTS #46 hyphen checks are off because they also reject "--"
+// in the third and fourth positions, as in "r3---sn-abc", which is in
+// common use; canonicalDomain still rejects leading and trailing hyphens.
+var idnaProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.BidiRule(),
+	idna.CheckHyphens(false),
+	idna.CheckJoiners(true),
+	idna.VerifyDNSLength(true),
+)
+
+// strictProfile is idnaProfile with the UTS #46 hyphen checks, used by the
+// linter to report "--" in the third and fourth positions
+var strictProfile = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.BidiRule(),
+	idna.CheckHyphens(true),
+	idna.CheckJoiners(true),
+	idna.VerifyDNSLength(true),
+)
+
+// canonicalDomain returns the canonical ASCII form of a domain name, so that
+// Unicode and punycode spellings of the same name compare equal. Labels
+// starting or ending with a hyphen are rejected.
+func canonicalDomain(s string) (string, error) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), ".")
+	if s == "" || strings.ContainsAny(s, " \t/:@,\"'") {
+		return "", errNotDomain
+	}
+	d, err := idnaProfile.ToASCII(s)
+	if err != nil {
+		return "", err
+	}
+	for _, label := range strings.Split(d, ".") {
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return "", fmt.Errorf("idna: invalid label %q: leading or trailing hyphen", label)
+		}
+	}
+	return d, nil
+}
+
+// unicodeDomain returns the Unicode form of a canonical domain for display;
+// plain ASCII domains are returned unchanged
+func unicodeDomain(ascii string) string {
+	if !strings.Contains(ascii, "xn--") {
+		return ascii
+	}
+	u, err := idnaProfile.ToUnicode(ascii)
+	if err != nil {
+		return ascii
+	}
+	return u
+}
+
+// isASCII reports whether s contains only ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
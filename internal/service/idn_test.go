@@ -0,0 +1,85 @@
+package service
+
+import "testing"
+
+func TestCanonicalDomain(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"Example.COM", "example.com", false},
+		{"ＴＥＭＰＭＡＩＬ.com", "tempmail.com", false},
+		{"bücher.de", "xn--bcher-kva.de", false},
+		{"xn--bcher-kva.de", "xn--bcher-kva.de", false},
+		{"BÜCHER.de", "xn--bcher-kva.de", false},
+		{"example.com.", "example.com", false},
+		{"  example.com ", "example.com", false},
+		{"r3---sn-abc.example.com", "r3---sn-abc.example.com", false},
+		{"", "", true},
+		{"a b.com", "", true},
+		{"user@example.com", "", true},
+		{"-bad.com", "", true},
+		{"bad-.com", "", true},
+		{"a..com", "", true},
+		{"xn--zz.com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := canonicalDomain(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("canonicalDomain(%q) error = %v, want error %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("canonicalDomain(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnicodeDomain(t *testing.T) {
+	tests := []struct {
+		ascii string
+		want  string
+	}{
+		{"example.com", "example.com"},
+		{"xn--bcher-kva.de", "bücher.de"},
+		{"mail.xn--bcher-kva.de", "mail.bücher.de"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ascii, func(t *testing.T) {
+			if got := unicodeDomain(tt.ascii); got != tt.want {
+				t.Errorf("unicodeDomain(%q) = %q, want %q", tt.ascii, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckMatchesIDNSpellings(t *testing.T) {
+	list := writeList(t, "list.txt", "bücher.de\nxn--mller-kva.com\n")
+	s := newTestService(t, Options{ListURLs: []string{list}})
+
+	tests := []struct {
+		email       string
+		wantDomain  string
+		wantUnicode string
+	}{
+		{"a@bücher.de", "xn--bcher-kva.de", "bücher.de"},
+		{"a@xn--bcher-kva.de", "xn--bcher-kva.de", "bücher.de"},
+		{"a@mail.BÜCHER.de", "mail.xn--bcher-kva.de", "mail.bücher.de"},
+		{"a@müller.com", "xn--mller-kva.com", "müller.com"},
+		{"a@ｍüller.com", "xn--mller-kva.com", "müller.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			v, err := s.Check(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !v.Disposable || v.Domain != tt.wantDomain || v.DomainUnicode != tt.wantUnicode {
+				t.Errorf("Check(%q) = %v %q %q, want disposable %q %q",
+					tt.email, v.Disposable, v.Domain, v.DomainUnicode, tt.wantDomain, tt.wantUnicode)
+			}
+		})
+	}
+}
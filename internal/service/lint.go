@@ -0,0 +1,103 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LintIssue is a problem found in a list file
+type LintIssue struct {
+	// Pos is the line number, or the 1-based item number for JSON lists
+	Pos     int
+	Entry   string
+	Problem string
+}
+
+// LintList parses data the way a list source named source would be parsed
+// (honouring a "<format>+" prefix) and reports every entry that is invalid
+// (including malformed internationalized names and rejected wildcard or
+// regular expression patterns), duplicated or not in canonical form. It
+// also returns the normalized entries.
+func LintList(source string, data []byte) ([]LintIssue, map[string]bool, error) {
+	format, location := splitFormat(source)
+	if format == "" {
+		format = detectFormat("", location, data)
+	}
+	scanner, ok := parsers[format]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported list format %q", format)
+	}
+
+	var issues []LintIssue
+	domains := make(map[string]bool)
+	firstSeen := make(map[string]int)
+	err := scanner.scanEntries(bytes.NewReader(data), func(pos int, raw string) {
+		if isPattern(raw) {
+			p, _, err := compilePattern(raw)
+			switch {
+			case err != nil:
+				issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: fmt.Sprintf("invalid pattern: %v", err)})
+			case firstSeen[p] > 0:
+				issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: fmt.Sprintf("duplicate of entry at %d", firstSeen[p])})
+			default:
+				firstSeen[p] = pos
+				domains[p] = true
+				if raw = strings.TrimSpace(raw); p != raw {
+					issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: fmt.Sprintf("not in canonical form, use %q", p)})
+				}
+			}
+			return
+		}
+
+		d, err := canonicalDomain(raw)
+		switch {
+		case errors.Is(err, errNotDomain) || (err == nil && !strings.Contains(d, ".")):
+			issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: "not a valid domain name"})
+			return
+		case err != nil:
+			issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: fmt.Sprintf("invalid domain name: %v", err)})
+			return
+		}
+
+		if first, dup := firstSeen[d]; dup {
+			issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: fmt.Sprintf("duplicate of entry at %d", first)})
+			return
+		}
+		firstSeen[d] = pos
+		domains[d] = true
+
+		raw = strings.TrimSpace(raw)
+		if _, err := strictProfile.ToASCII(raw); err != nil {
+			issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: "hyphens not allowed by UTS #46: " + strings.TrimPrefix(err.Error(), "idna: ")})
+			return
+		}
+		switch {
+		case !isASCII(raw):
+			issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: fmt.Sprintf("internationalized domain name should be listed in punycode as %q", d)})
+		case d != raw:
+			issues = append(issues, LintIssue{Pos: pos, Entry: raw, Problem: fmt.Sprintf("not in canonical form, use %q", d)})
+		}
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", format, err)
+	}
+
+	return issues, domains, nil
+}
+
+// LoadList fetches and parses a single list source (an HTTP(S) URL, a file
+// or a directory, with an optional "<format>+" prefix) exactly as a
+// refresh would, without a service, its caching or its metrics
+func LoadList(source string) (map[string]bool, error) {
+	format, location := splitFormat(source)
+	if path, ok := localPath(location); ok {
+		domains, _, _, err := fetchFromFile(format, path, "", normalizeListEntry)
+		return domains, err
+	}
+	domains, _, _, err := fetchFromURL(&http.Client{Timeout: 30 * time.Second}, source, "", normalizeListEntry)
+	return domains, err
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestLintList(t *testing.T) {
+	data := []byte(strings.Join([]string{
+		"tempmail.com",
+		"TempMail.com",
+		"Mailinator.COM",
+		"bücher.de",
+		"ab--cd.com",
+		"-bad.com",
+		"localhost",
+		"*.com",
+		"*.spam.*",
+		"",
+	}, "\n"))
+	issues, domains, err := LintList("list.txt", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pos     int
+		problem string
+	}{
+		{2, "duplicate of entry at 1"},
+		{3, `not in canonical form, use "mailinator.com"`},
+		{4, `should be listed in punycode as "xn--bcher-kva.de"`},
+		{5, "hyphens not allowed by UTS #46"},
+		{6, "invalid domain name"},
+		{7, "not a valid domain name"},
+		{8, "invalid pattern"},
+	}
+	if len(issues) != len(tests) {
+		t.Fatalf("LintList() issues = %+v, want %d", issues, len(tests))
+	}
+	for i, tt := range tests {
+		if issues[i].Pos != tt.pos || !strings.Contains(issues[i].Problem, tt.problem) {
+			t.Errorf("issue %d = %+v, want line %d %q", i, issues[i], tt.pos, tt.problem)
+		}
+	}
+
+	want := []string{"*.spam.*", "ab--cd.com", "mailinator.com", "tempmail.com", "xn--bcher-kva.de"}
+	if got := slices.Sorted(maps.Keys(domains)); !slices.Equal(got, want) {
+		t.Errorf("domains = %q, want %q", got, want)
+	}
+}
+
+func TestLoadList(t *testing.T) {
+	var broken atomic.Bool
+	tests := []struct {
+		name   string
+		source string
+		want   []string
+	}{
+		{"file", writeList(t, "list.txt", "a.com\nB.com # comment\n"), []string{"a.com", "b.com"}},
+		{"forced format", "hosts+" + writeList(t, "list.txt", "0.0.0.0 a.com b.com\n"), []string{"a.com", "b.com"}},
+		{"url", listServer(t, "a.com\n*.spam.*\n", &broken), []string{"*.spam.*", "a.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domains, err := LoadList(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Sorted(maps.Keys(domains)); !slices.Equal(got, tt.want) {
+				t.Errorf("LoadList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	broken.Store(true)
+	if _, err := LoadList(listServer(t, "a.com\n", &broken)); err == nil {
+		t.Error("LoadList() of a failing URL = nil error")
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// defaultRoleAccounts are local parts that name a function rather than a
+// person
+var defaultRoleAccounts = []string{
+	"abuse", "admin", "administrator", "billing", "contact", "help", "hello",
+	"hostmaster", "info", "mail", "marketing", "no-reply", "noreply", "office",
+	"postmaster", "root", "sales", "security", "support", "team", "webmaster",
+}
+
+// Thresholds of the machine-generated local part heuristic. Letters and
+// digits are judged separately: digits say nothing about how pronounceable
+// the letters are.
+const (
+	// randomMinLetters is the minimum number of letters for the letter check
+	randomMinLetters = 12
+	// DefaultRandomMinEntropy is the letter entropy, in bits, from which a
+	// local part with few vowels and many rare letters looks random
+	DefaultRandomMinEntropy = 3.0
+	// randomMaxVowelShare is the share of vowels among letters above which a
+	// local part looks pronounceable
+	randomMaxVowelShare = 0.25
+	// randomMinRareShare is the share of rare letters (j, k, q, v, w, x, z)
+	// among letters below which a local part looks like a name
+	randomMinRareShare = 0.15
+	// randomMinSwitches is the number of changes between letters and digits
+	// from which a local part looks generated ("a8f3k2j9d0s1")
+	randomMinSwitches = 6
+)
+
+// LocalPartOptions configures the local part checks
+type LocalPartOptions struct {
+	// RoleAccounts replaces the built-in role account names when not empty
+	RoleAccounts []string
+	// RandomMinEntropy is the letter entropy threshold of the randomness
+	// check; 0 uses DefaultRandomMinEntropy
+	RandomMinEntropy float64
+	// RandomDigitRun, when positive, also treats a run of that many digits
+	// as random ("user483920" with 6). Off by default: phone numbers and
+	// birth dates are common in personal addresses.
+	RandomDigitRun int
+	// Patterns are RE2 regular expressions matched against the whole
+	// lowercase local part, without its "+tag"
+	Patterns []string
+}
+
+// localPartPattern is a compiled local part pattern
+type localPartPattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// LocalPartChecker finds role accounts, randomly generated local parts and
+// local parts matching configured patterns. Its findings are recorded on the
+// verdict; whether they reject an address is decided per hook.
+type LocalPartChecker struct {
+	roles      map[string]bool
+	minEntropy float64
+	digitRun   int
+	patterns   []localPartPattern
+}
+
+// NewLocalPartChecker validates opts and compiles the patterns
+func NewLocalPartChecker(opts LocalPartOptions) (*LocalPartChecker, error) {
+	roles := opts.RoleAccounts
+	if len(roles) == 0 {
+		roles = defaultRoleAccounts
+	}
+	c := &LocalPartChecker{
+		roles:      make(map[string]bool, len(roles)),
+		minEntropy: opts.RandomMinEntropy,
+		digitRun:   opts.RandomDigitRun,
+	}
+	for _, role := range roles {
+		if role = strings.ToLower(strings.TrimSpace(role)); role != "" {
+			c.roles[role] = true
+		}
+	}
+	if c.minEntropy == 0 {
+		c.minEntropy = DefaultRandomMinEntropy
+	}
+	if c.minEntropy < 0 {
+		return nil, fmt.Errorf("invalid entropy threshold %g: must not be negative", c.minEntropy)
+	}
+	if c.digitRun < 0 {
+		return nil, fmt.Errorf("invalid digit run %d: must not be negative", c.digitRun)
+	}
+
+	for _, pattern := range opts.Patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if len(pattern) > maxPatternLength {
+			return nil, fmt.Errorf("local part pattern %q: longer than %d characters", pattern, maxPatternLength)
+		}
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("local part pattern %q: %w", pattern, err)
+		}
+		c.patterns = append(c.patterns, localPartPattern{pattern: pattern, re: re})
+	}
+	return c, nil
+}
+
+// check records the findings for a local part on the verdict
+func (c *LocalPartChecker) check(local string, v *domain.Verdict) {
+	local = localPartBase(local)
+	if c.roles[local] {
+		v.RoleAccount = local
+	}
+	if reason, ok := c.random(local); ok {
+		v.RandomLocalPart = reason
+	}
+	for _, p := range c.patterns {
+		if p.re.MatchString(local) {
+			v.LocalPartPattern = p.pattern
+			break
+		}
+	}
+}
+
+// localPartBase lowercases a local part and strips its "+tag" subaddress
+func localPartBase(local string) string {
+	local = strings.ToLower(local)
+	if i := strings.IndexByte(local, '+'); i > 0 {
+		local = local[:i]
+	}
+	return local
+}
+
+// random reports whether a local part looks machine-generated: letters
+// with few vowels, many rare letters and high entropy ("xkqzvtrwmjpb"),
+// frequent changes between letters and digits ("a8f3k2j9d0s1") or, when
+// enabled, a long run of digits. Separators are ignored.
+func (c *LocalPartChecker) random(local string) (string, bool) {
+	var (
+		letters         []byte
+		vowels, rare    int
+		run, longest    int
+		switches        int
+		lastDigit, seen bool
+	)
+	for i := 0; i < len(local); i++ {
+		ch := local[i]
+		isDigit := ch >= '0' && ch <= '9'
+		if !isDigit && (ch < 'a' || ch > 'z') {
+			run = 0
+			continue
+		}
+		if seen && isDigit != lastDigit {
+			switches++
+		}
+		seen, lastDigit = true, isDigit
+		if isDigit {
+			run++
+			longest = max(longest, run)
+			continue
+		}
+		run = 0
+		letters = append(letters, ch)
+		switch {
+		case strings.IndexByte("aeiouy", ch) >= 0:
+			vowels++
+		case strings.IndexByte("jkqvwxz", ch) >= 0:
+			rare++
+		}
+	}
+
+	switch {
+	case switches >= randomMinSwitches:
+		return "mixed letters and digits", true
+	case c.digitRun > 0 && longest >= c.digitRun:
+		return "long digit run", true
+	case len(letters) >= randomMinLetters && entropy(letters) >= c.minEntropy &&
+		float64(vowels)/float64(len(letters)) <= randomMaxVowelShare &&
+		float64(rare)/float64(len(letters)) >= randomMinRareShare:
+		return "random characters", true
+	}
+	return "", false
+}
+
+// entropy returns the Shannon entropy of s in bits per character
+func entropy(s []byte) float64 {
+	var counts [256]int
+	for _, ch := range s {
+		counts[ch]++
+	}
+	var h float64
+	for _, n := range counts {
+		if n == 0 {
+			continue
+		}
+		p := float64(n) / float64(len(s))
+		h -= p * math.Log2(p)
+	}
+	return h
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+func TestLocalPartCheckerRandom(t *testing.T) {
+	c, err := NewLocalPartChecker(LocalPartOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		local string
+		want  string
+	}{
+		// Personal addresses
+		{"john.smith1985", ""},
+		{"jane_doe_1990", ""},
+		{"4155551234", ""},
+		{"user483920", ""},
+		{"christopherschmidt", ""},
+		{"maximiliansteinberger", ""},
+		{"brandtschwartzberg", ""},
+		{"przemyslawkrzyzanowski", ""},
+		{"wojciechszczepanski", ""},
+		{"zbigniewkowalczyk", ""},
+		{"nguyenthanhtruong", ""},
+		{"jwkowalski88", ""},
+		{"k.jackowski1977", ""},
+		{"r2d2fan", ""},
+		{"a.b", ""},
+		// Generated
+		{"a8f3k2j9d0s1", "mixed letters and digits"},
+		{"3f9a2c1e7b4d5a6f", "mixed letters and digits"},
+		{"xkqzvtrwmjpb", "random characters"},
+		{"qzx.kvb.trmwplcd", "random characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.local, func(t *testing.T) {
+			got, _ := c.random(tt.local)
+			if got != tt.want {
+				t.Errorf("random(%q) = %q, want %q", tt.local, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalPartCheckerDigitRun(t *testing.T) {
+	c, err := NewLocalPartChecker(LocalPartOptions{RandomDigitRun: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		local string
+		want  string
+	}{
+		{"user483920", ""},
+		{"john.smith1985", ""},
+		{"user48392017", "long digit run"},
+		{"4155551234", "long digit run"},
+	}
+	for _, tt := range tests {
+		if got, _ := c.random(tt.local); got != tt.want {
+			t.Errorf("random(%q) = %q, want %q", tt.local, got, tt.want)
+		}
+	}
+}
+
+func TestLocalPartCheckerCheck(t *testing.T) {
+	c, err := NewLocalPartChecker(LocalPartOptions{Patterns: []string{"test[0-9]*", `qa\.[a-z]+`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		local       string
+		wantRole    string
+		wantPattern string
+	}{
+		{"admin", "admin", ""},
+		{"Admin+signup", "admin", ""},
+		{"noreply", "noreply", ""},
+		{"postmaster", "postmaster", ""},
+		{"administrator.smith", "", ""},
+		{"test", "", "test[0-9]*"},
+		{"test42+x", "", "test[0-9]*"},
+		{"mytest42", "", ""},
+		{"qa.alice", "", `qa\.[a-z]+`},
+		{"jane", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.local, func(t *testing.T) {
+			var v domain.Verdict
+			c.check(tt.local, &v)
+			if v.RoleAccount != tt.wantRole {
+				t.Errorf("RoleAccount = %q, want %q", v.RoleAccount, tt.wantRole)
+			}
+			if v.LocalPartPattern != tt.wantPattern {
+				t.Errorf("LocalPartPattern = %q, want %q", v.LocalPartPattern, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestLocalPartCheckerCustomRoles(t *testing.T) {
+	c, err := NewLocalPartChecker(LocalPartOptions{RoleAccounts: []string{" HR ", "jobs"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for local, want := range map[string]string{"hr": "hr", "jobs": "jobs", "admin": ""} {
+		var v domain.Verdict
+		c.check(local, &v)
+		if v.RoleAccount != want {
+			t.Errorf("check(%q).RoleAccount = %q, want %q", local, v.RoleAccount, want)
+		}
+	}
+}
+
+func TestNewLocalPartCheckerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts LocalPartOptions
+	}{
+		{"invalid pattern", LocalPartOptions{Patterns: []string{"(("}}},
+		{"negative entropy", LocalPartOptions{RandomMinEntropy: -1}},
+		{"negative digit run", LocalPartOptions{RandomDigitRun: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLocalPartChecker(tt.opts); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
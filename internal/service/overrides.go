@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Override sources reported in verdicts for inline (environment) entries
+const (
+	inlineAllowOrigin = "DISPOSABLE_ALLOW_DOMAINS"
+	inlineDenyOrigin  = "DISPOSABLE_DENY_DOMAINS"
+)
+
+// Overrides are local allow/deny sources layered over the fetched lists.
+// Allow entries always take precedence over deny entries and the lists.
+type Overrides struct {
+	AllowFiles   []string
+	AllowDomains []string
+	DenyFiles    []string
+	DenyDomains  []string
+}
+
+// load reads all configured allow/deny sources.
+// The returned maps are keyed by domain with the originating source as value.
+func (o Overrides) load() (allow, deny map[string]string, err error) {
+	allow, err = loadOverrideEntries(o.AllowFiles, o.AllowDomains, inlineAllowOrigin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("allowlist: %w", err)
+	}
+
+	deny, err = loadOverrideEntries(o.DenyFiles, o.DenyDomains, inlineDenyOrigin)
+	if err != nil {
+		return nil, nil, fmt.Errorf("denylist: %w", err)
+	}
+
+	return allow, deny, nil
+}
+
+// Validate checks the inline allow/deny entries, which are fixed for the
+// lifetime of the process, so a typo stops startup instead of being skipped
+func (o Overrides) Validate() error {
+	for _, list := range []struct {
+		origin  string
+		entries []string
+	}{
+		{inlineAllowOrigin, o.AllowDomains},
+		{inlineDenyOrigin, o.DenyDomains},
+	} {
+		var invalid []string
+		for _, entry := range list.entries {
+			if _, ok := normalizeEntry(entry); !ok {
+				invalid = append(invalid, fmt.Sprintf("%q", entry))
+			}
+		}
+		if len(invalid) > 0 {
+			return fmt.Errorf("%s: invalid domains %s", list.origin, strings.Join(invalid, ", "))
+		}
+	}
+	return nil
+}
+
+// isEmpty reports whether no override source is configured
+func (o Overrides) isEmpty() bool {
+	return len(o.AllowFiles) == 0 && len(o.AllowDomains) == 0 &&
+		len(o.DenyFiles) == 0 && len(o.DenyDomains) == 0
+}
+
+// loadOverrideEntries merges domains from files and inline values. Invalid
+// inline values are skipped; Validate reports them at startup.
+func loadOverrideEntries(files, inline []string, inlineOrigin string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	for _, file := range files {
+		format, path := splitFormat(file)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		domains, err := parseList(format, "", path, data, normalizeEntry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for d := range domains {
+			if _, exists := entries[d]; !exists {
+				entries[d] = path
+			}
+		}
+	}
+
+	for _, entry := range inline {
+		d, ok := normalizeEntry(entry)
+		if !ok {
+			continue
+		}
+		if _, exists := entries[d]; !exists {
+			entries[d] = inlineOrigin
+		}
+	}
+
+	return entries, nil
+}
+
+// refreshOverrides reloads the local allow/deny sources.
+// On failure the previously loaded overrides are kept.
+func (s *DisposableEmailService) refreshOverrides() {
+	if s.overrides.isEmpty() {
+		return
+	}
+
+	allow, deny, err := s.overrides.load()
+	if err != nil {
+		s.logger.Error("failed to load local overrides - keeping previous entries",
+			slog.Any("error", err))
+		return
+	}
+
+	s.mu.Lock()
+	s.allow = allow
+	s.deny = deny
+	s.mu.Unlock()
+
+	s.logger.Info("local overrides loaded",
+		slog.Int("allow_count", len(allow)),
+		slog.Int("deny_count", len(deny)))
+}
+
+// lookupOverride returns the first candidate present in entries
+func lookupOverride(entries map[string]string, candidates []string) (string, string, bool) {
+	for _, candidate := range candidates {
+		if origin, ok := entries[candidate]; ok {
+			return candidate, origin, true
+		}
+	}
+	return "", "", false
+}
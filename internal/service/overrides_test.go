@@ -0,0 +1,88 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+func TestOverrides(t *testing.T) {
+	list := writeList(t, "list.txt", "tempmail.com\nmailinator.com\n")
+	allowFile := writeList(t, "allow.txt", "# trusted partners\nmailinator.com\n")
+	denyFile := writeList(t, "deny.hosts", "0.0.0.0 spam.example\n")
+	s := newTestService(t, Options{
+		ListURLs: []string{list},
+		Overrides: Overrides{
+			AllowFiles:   []string{allowFile},
+			AllowDomains: []string{"Partner.COM"},
+			DenyFiles:    []string{"hosts+" + denyFile},
+			DenyDomains:  []string{"throwaway.io", "partner.com"},
+		},
+	})
+
+	tests := []struct {
+		email          string
+		wantDisposable bool
+		wantSource     string
+		wantOrigin     string
+	}{
+		{"a@mailinator.com", false, domain.SourceAllowlist, allowFile},
+		{"a@sub.partner.com", false, domain.SourceAllowlist, inlineAllowOrigin},
+		{"a@spam.example", true, domain.SourceDenylist, denyFile},
+		{"a@x.throwaway.io", true, domain.SourceDenylist, inlineDenyOrigin},
+		{"a@tempmail.com", true, domain.SourceList, list},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			v, err := s.Check(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Disposable != tt.wantDisposable || v.Source != tt.wantSource || v.Origin != tt.wantOrigin {
+				t.Errorf("Check(%q) = %v/%s/%s, want %v/%s/%s",
+					tt.email, v.Disposable, v.Source, v.Origin, tt.wantDisposable, tt.wantSource, tt.wantOrigin)
+			}
+		})
+	}
+
+	// A broken file keeps the previously loaded entries
+	if err := os.Remove(allowFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := s.Check("a@mailinator.com"); v.Source != domain.SourceAllowlist {
+		t.Errorf("allow entry dropped after a failed reload: %+v", v)
+	}
+}
+
+func TestOverridesValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides Overrides
+		wantErr   string
+	}{
+		{"valid", Overrides{AllowDomains: []string{"a.com", "Bücher.de"}, DenyDomains: []string{"b.com"}}, ""},
+		{"invalid allow", Overrides{AllowDomains: []string{"a.com", "localhost", "not a domain"}}, `DISPOSABLE_ALLOW_DOMAINS: invalid domains "localhost", "not a domain"`},
+		{"invalid deny", Overrides{DenyDomains: []string{"-bad.com"}}, `DISPOSABLE_DENY_DOMAINS: invalid domains "-bad.com"`},
+		// Files are read on every refresh and may change
+		{"files are not read", Overrides{AllowFiles: []string{"/nonexistent"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.overrides.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
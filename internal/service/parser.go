@@ -0,0 +1,344 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Supported list formats. A source can force a format with a "<format>+"
+// prefix, e.g. "json+https://example.com/list" or "hosts+/etc/hosts".
+const (
+	FormatTxt     = "txt"
+	FormatJSON    = "json"
+	FormatHosts   = "hosts"
+	FormatCSV     = "csv"
+	FormatAdblock = "adblock"
+)
+
+// entryScanner parses a domain list in a specific format. It reports each
+// raw entry before normalization with its position: the line number, or the
+// 1-based item number for JSON.
+type entryScanner interface {
+	scanEntries(r io.Reader, fn func(pos int, raw string)) error
+}
+
+var parsers = map[string]entryScanner{
+	FormatTxt:     txtParser{},
+	FormatJSON:    jsonParser{},
+	FormatHosts:   hostsParser{},
+	FormatCSV:     csvParser{},
+	FormatAdblock: adblockParser{},
+}
+
+// splitFormat separates an explicit "<format>+" prefix from a source
+func splitFormat(source string) (format, location string) {
+	if i := strings.IndexByte(source, '+'); i > 0 {
+		if _, ok := parsers[source[:i]]; ok {
+			return source[:i], source[i+1:]
+		}
+	}
+	return "", source
+}
+
+// parseList parses data with the given format, detecting it when empty, and
+// converts every entry with normalize
+func parseList(format, contentType, location string, data []byte, normalize entryNormalizer) (map[string]bool, error) {
+	if format == "" {
+		format = detectFormat(contentType, location, data)
+	}
+
+	scanner, ok := parsers[format]
+	if !ok {
+		return nil, fmt.Errorf("unsupported list format %q", format)
+	}
+
+	domains, err := collectEntries(scanner, bytes.NewReader(data), normalize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", format, err)
+	}
+	return domains, nil
+}
+
+// detectFormat picks a format from the content type, then the file
+// extension, and finally by sniffing the first meaningful line
+func detectFormat(contentType, location string, data []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch mediaType {
+		case "application/json":
+			return FormatJSON
+		case "text/csv":
+			return FormatCSV
+		}
+	}
+
+	name := location
+	if u, err := url.Parse(location); err == nil && u.Path != "" {
+		name = u.Path
+	}
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return FormatJSON
+	case ".csv":
+		return FormatCSV
+	case ".hosts":
+		return FormatHosts
+	}
+	if path.Base(name) == "hosts" {
+		return FormatHosts
+	}
+
+	return sniffFormat(data)
+}
+
+// sniffLineLimit is how much of a line sniffFormat looks at
+const sniffLineLimit = 4 << 10
+
+// sniffFormat guesses the format from the first non-comment line. Lines are
+// cut from data directly: a minified JSON list is a single line of any size.
+func sniffFormat(data []byte) string {
+	for len(data) > 0 {
+		var raw []byte
+		raw, data, _ = bytes.Cut(data, []byte("\n"))
+		line := string(bytes.TrimSpace(raw[:min(len(raw), sniffLineLimit)]))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case line[0] == '[' && strings.HasPrefix(strings.ToLower(line), "[adblock"):
+			return FormatAdblock
+		case line[0] == '[' || line[0] == '{':
+			return FormatJSON
+		case line[0] == '!' || strings.HasPrefix(line, "||"):
+			return FormatAdblock
+		case net.ParseIP(strings.Fields(line)[0]) != nil:
+			return FormatHosts
+		case isRegexEntry(line):
+			// Regular expression entries may contain commas
+			return FormatTxt
+		case strings.Contains(line, ","):
+			return FormatCSV
+		}
+		return FormatTxt
+	}
+	return FormatTxt
+}
+
+// normalizeEntry converts a list entry to its canonical IDNA form and rejects
+// anything that is not a valid domain name with at least two labels
+func normalizeEntry(s string) (string, bool) {
+	d, err := canonicalDomain(s)
+	if err != nil || !strings.Contains(d, ".") {
+		return "", false
+	}
+	return d, true
+}
+
+// stripComment removes an inline "#" comment
+func stripComment(line string) string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// scanLines calls fn for every line of r with its 1-based line number. A
+// line may be as long as a whole list.
+func scanLines(r io.Reader, fn func(n int, line string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxListSize)
+	n := 0
+	for scanner.Scan() {
+		n++
+		fn(n, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to scan file: %w", err)
+	}
+	return nil
+}
+
+// entryNormalizer converts a raw list entry to its canonical form, or
+// reports that it is not a valid entry
+type entryNormalizer func(raw string) (string, bool)
+
+// collectEntries normalizes the entries reported by p into a set
+func collectEntries(p entryScanner, r io.Reader, normalize entryNormalizer) (map[string]bool, error) {
+	domains := make(map[string]bool)
+	err := p.scanEntries(r, func(_ int, raw string) {
+		if d, ok := normalize(raw); ok {
+			domains[d] = true
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+// txtParser reads one domain per line with "#" comments
+type txtParser struct{}
+
+func (txtParser) scanEntries(r io.Reader, fn func(pos int, raw string)) error {
+	return scanLines(r, func(n int, line string) {
+		if entry := stripComment(line); entry != "" {
+			fn(n, entry)
+		}
+	})
+}
+
+// hostsLocalNames are the loopback aliases found in most hosts files
+var hostsLocalNames = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+}
+
+// hostsParser reads hosts-file lines such as "0.0.0.0 a.com b.com # comment"
+type hostsParser struct{}
+
+func (hostsParser) scanEntries(r io.Reader, fn func(pos int, raw string)) error {
+	return scanLines(r, func(n int, line string) {
+		fields := strings.Fields(stripComment(line))
+		if len(fields) < 2 || net.ParseIP(fields[0]) == nil {
+			return
+		}
+		for _, field := range fields[1:] {
+			if !hostsLocalNames[strings.ToLower(field)] {
+				fn(n, field)
+			}
+		}
+	})
+}
+
+// adblockParser reads Adblock-style "||domain^" rules. Exceptions ("@@"),
+// cosmetic filters and rules with paths are ignored.
+type adblockParser struct{}
+
+func (adblockParser) scanEntries(r io.Reader, fn func(pos int, raw string)) error {
+	return scanLines(r, func(n int, line string) {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "||") {
+			return
+		}
+		rule := line[2:]
+		if i := strings.IndexByte(rule, '$'); i >= 0 {
+			rule = rule[:i]
+		}
+		fn(n, strings.TrimSuffix(rule, "^"))
+	})
+}
+
+// csvParser reads the domain column of a CSV file. The column is taken from
+// a header named "domain" (or "hostname"/"host") when present, otherwise the
+// first column is used.
+type csvParser struct{}
+
+func (csvParser) scanEntries(r io.Reader, fn func(pos int, raw string)) error {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	column := 0
+	first := true
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read csv: %w", err)
+		}
+
+		if first {
+			first = false
+			if i := csvDomainColumn(record); i >= 0 {
+				column = i
+				continue
+			}
+		}
+
+		if column < len(record) {
+			if entry := stripComment(record[column]); entry != "" {
+				line, _ := reader.FieldPos(column)
+				fn(line, entry)
+			}
+		}
+	}
+}
+
+// csvDomainColumn returns the index of the domain column in a header row
+func csvDomainColumn(header []string) int {
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "domain", "domains", "domain_name", "hostname", "host":
+			return i
+		}
+	}
+	return -1
+}
+
+// jsonParser reads a JSON array of domains. Array items may be strings or
+// objects with a "domain" field, and the array may be wrapped in an object
+// under a "domains" key.
+type jsonParser struct{}
+
+func (jsonParser) scanEntries(r io.Reader, fn func(pos int, raw string)) error {
+	var doc json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode json: %w", err)
+	}
+
+	trimmed := bytes.TrimSpace(doc)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Domains json.RawMessage `json:"domains"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return fmt.Errorf("failed to decode json: %w", err)
+		}
+		if wrapper.Domains == nil {
+			return fmt.Errorf("json object has no \"domains\" array")
+		}
+		trimmed = wrapper.Domains
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(trimmed, &items); err != nil {
+		return fmt.Errorf("expected a json array: %w", err)
+	}
+
+	for i, item := range items {
+		var entry string
+		if err := json.Unmarshal(item, &entry); err != nil {
+			var obj struct {
+				Domain string `json:"domain"`
+			}
+			if json.Unmarshal(item, &obj) != nil {
+				continue
+			}
+			entry = obj.Domain
+		}
+		fn(i+1, entry)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseList(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+		want   []string
+	}{
+		{
+			name:   "txt",
+			format: FormatTxt,
+			data:   "# comment\ntempmail.com\n\n  Mailinator.COM  # inline\nnot a domain\nlocalhost\n",
+			want:   []string{"mailinator.com", "tempmail.com"},
+		},
+		{
+			name:   "json array",
+			format: FormatJSON,
+			data:   `["tempmail.com", {"domain": "mailinator.com"}, 42]`,
+			want:   []string{"mailinator.com", "tempmail.com"},
+		},
+		{
+			name:   "json object",
+			format: FormatJSON,
+			data:   `{"domains": ["tempmail.com"]}`,
+			want:   []string{"tempmail.com"},
+		},
+		{
+			name:   "hosts",
+			format: FormatHosts,
+			data:   "127.0.0.1 localhost\n0.0.0.0 tempmail.com www.tempmail.com # ads\n::1 ip6-localhost\nbogus line\n",
+			want:   []string{"tempmail.com", "www.tempmail.com"},
+		},
+		{
+			name:   "csv with header",
+			format: FormatCSV,
+			data:   "id,domain,added\n1,tempmail.com,2024\n2,mailinator.com,2024\n",
+			want:   []string{"mailinator.com", "tempmail.com"},
+		},
+		{
+			name:   "csv without header",
+			format: FormatCSV,
+			data:   "tempmail.com,note\nmailinator.com\n",
+			want:   []string{"mailinator.com", "tempmail.com"},
+		},
+		{
+			name:   "adblock",
+			format: FormatAdblock,
+			data:   "[Adblock Plus 2.0]\n! comment\n||tempmail.com^\n||mailinator.com^$third-party\n@@||allowed.com^\nexample.com##.ad\n",
+			want:   []string{"mailinator.com", "tempmail.com"},
+		},
+		{
+			name:   "idn",
+			format: FormatTxt,
+			data:   "bücher.de\nxn--bcher-kva.de\n",
+			want:   []string{"xn--bcher-kva.de"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domains, err := parseList(tt.format, "", "", []byte(tt.data), normalizeEntry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Sorted(maps.Keys(domains)); !slices.Equal(got, tt.want) {
+				t.Errorf("parseList() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseListErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		data   string
+	}{
+		{"malformed json", FormatJSON, `["tempmail.com"`},
+		{"json object without domains", FormatJSON, `{"items": []}`},
+		{"json scalar", FormatJSON, `"tempmail.com"`},
+		{"unknown format", "xml", "<domains/>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseList(tt.format, "", "", []byte(tt.data), normalizeEntry); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		location    string
+		data        string
+		want        string
+	}{
+		{"json content type", "application/json; charset=utf-8", "https://example.com/list", "tempmail.com", FormatJSON},
+		{"csv content type", "text/csv", "https://example.com/list", "tempmail.com", FormatCSV},
+		{"json extension", "text/plain", "https://example.com/list.json?v=1", "tempmail.com", FormatJSON},
+		{"csv extension", "", "/lists/deny.CSV", "tempmail.com", FormatCSV},
+		{"hosts extension", "", "/lists/block.hosts", "tempmail.com", FormatHosts},
+		{"hosts file", "", "/etc/hosts", "tempmail.com", FormatHosts},
+		{"sniff json", "", "https://example.com/list", "# header\n[\"tempmail.com\"]", FormatJSON},
+		{"sniff json object", "", "", `{"domains": []}`, FormatJSON},
+		{"sniff adblock header", "", "", "[Adblock Plus 2.0]\n||tempmail.com^", FormatAdblock},
+		{"sniff adblock rule", "", "", "||tempmail.com^", FormatAdblock},
+		{"sniff adblock comment", "", "", "! Title\n||tempmail.com^", FormatAdblock},
+		{"sniff hosts", "", "", "# hosts\n0.0.0.0 tempmail.com", FormatHosts},
+		{"sniff csv", "", "", "domain,added\ntempmail.com,2024", FormatCSV},
+		{"sniff regex with comma", "", "", `/^mail[0-9]{1,3}\.xyz$/`, FormatTxt},
+		{"sniff txt", "", "", "tempmail.com\nmailinator.com", FormatTxt},
+		{"empty", "", "", "", FormatTxt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectFormat(tt.contentType, tt.location, []byte(tt.data)); got != tt.want {
+				t.Errorf("detectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLongLines(t *testing.T) {
+	// Minified lists are a single line far longer than bufio's default token
+	items := make([]string, 20000)
+	for i := range items {
+		items[i] = `"d` + strings.Repeat("x", i%20) + `.example.com"`
+	}
+	minified := "[" + strings.Join(items, ",") + "]"
+	if len(minified) < 256<<10 {
+		t.Fatalf("test list is only %d bytes", len(minified))
+	}
+
+	if got := sniffFormat([]byte(minified)); got != FormatJSON {
+		t.Errorf("sniffFormat(minified json) = %q, want %q", got, FormatJSON)
+	}
+
+	long := "tempmail.com\n" + strings.Repeat("a", 100<<10) + ".com\nmailinator.com\n"
+	domains, err := parseList(FormatTxt, "", "", []byte(long), normalizeEntry)
+	if err != nil {
+		t.Fatalf("parseList() with a 100KB line: %v", err)
+	}
+	if !domains["tempmail.com"] || !domains["mailinator.com"] {
+		t.Errorf("entries around a 100KB line were lost: %v", slices.Sorted(maps.Keys(domains)))
+	}
+}
+
+func TestSplitFormat(t *testing.T) {
+	tests := []struct {
+		source, wantFormat, wantLocation string
+	}{
+		{"json+https://example.com/list", FormatJSON, "https://example.com/list"},
+		{"hosts+/etc/hosts", FormatHosts, "/etc/hosts"},
+		{"https://example.com/a+b.txt", "", "https://example.com/a+b.txt"},
+		{"/lists/deny.txt", "", "/lists/deny.txt"},
+	}
+	for _, tt := range tests {
+		format, location := splitFormat(tt.source)
+		if format != tt.wantFormat || location != tt.wantLocation {
+			t.Errorf("splitFormat(%q) = %q, %q; want %q, %q", tt.source, format, location, tt.wantFormat, tt.wantLocation)
+		}
+	}
+}
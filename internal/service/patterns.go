@@ -0,0 +1,274 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Pattern limits guard against entries that are expensive to compile or
+// match, and against entries so broad that they would block ordinary
+// mailbox providers
+const (
+	maxPatternLength = 253
+	// maxPatternInsts caps the size of a compiled pattern program
+	maxPatternInsts = 1000
+	// minGlobLiterals is the minimum number of non-wildcard characters in a
+	// glob, and of literal characters every match of a regular expression
+	// entry must contain
+	minGlobLiterals = 4
+)
+
+// patternProbes are domains of common mailbox providers; a pattern matching
+// any of them, or any of freeMailDomains, is rejected as too broad
+var patternProbes = probeDomains(
+	"gmail.com",
+	"googlemail.com",
+	"outlook.com",
+	"hotmail.com",
+	"yahoo.com",
+	"icloud.com",
+	"proton.me",
+	"gmx.de",
+	"example.com",
+)
+
+// probeDomains returns domains followed by the remaining free mailbox
+// providers, sorted so the reported match does not vary between runs
+func probeDomains(domains ...string) []string {
+	var rest []string
+	for d := range freeMailDomains {
+		if !slices.Contains(domains, d) {
+			rest = append(rest, d)
+		}
+	}
+	sort.Strings(rest)
+	return append(domains, rest...)
+}
+
+// globLabels matches one or more labels for a "*" label
+const globLabels = `[a-z0-9-]+(?:\.[a-z0-9-]+)*`
+
+// isPattern reports whether a list entry is a glob ("*.tempmail.*") or a
+// regular expression ("/^mail[0-9]+\.xyz$/") rather than a domain
+func isPattern(entry string) bool {
+	entry = strings.TrimSpace(entry)
+	return strings.Contains(entry, "*") || isRegexEntry(entry)
+}
+
+// isRegexEntry reports whether entry is a regular expression in slashes
+func isRegexEntry(entry string) bool {
+	return len(entry) > 2 && entry[0] == '/' && entry[len(entry)-1] == '/'
+}
+
+// compilePattern validates a glob or regular expression entry and returns
+// its canonical form and its anchored, compiled form.
+//
+// In globs a "*" label matches one or more labels and a "*" inside a label
+// matches any run of characters within that label, so "*.tempmail.*"
+// matches "a.tempmail.com" and "a.b.tempmail.co.uk", and "mail*.xyz"
+// matches "mail123.xyz". Regular expressions use RE2 syntax and always
+// match the whole domain. Both match the lowercase ASCII (punycode) form.
+func compilePattern(entry string) (string, *regexp.Regexp, error) {
+	entry = strings.TrimSpace(entry)
+	if len(entry) > maxPatternLength {
+		return "", nil, fmt.Errorf("longer than %d characters", maxPatternLength)
+	}
+
+	var canonical, expr string
+	regex := isRegexEntry(entry)
+	if regex {
+		canonical, expr = entry, entry[1:len(entry)-1]
+	} else {
+		var err error
+		if canonical, expr, err = globToRegexp(entry); err != nil {
+			return "", nil, err
+		}
+	}
+
+	parsed, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	if regex && requiredLiterals(parsed) < minGlobLiterals {
+		return "", nil, fmt.Errorf("needs at least %d literal characters besides dots", minGlobLiterals)
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	if len(prog.Inst) > maxPatternInsts {
+		return "", nil, errors.New("too complex")
+	}
+
+	re, err := regexp.Compile(`^(?:` + expr + `)$`)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	for _, probe := range patternProbes {
+		if re.MatchString(probe) {
+			return "", nil, fmt.Errorf("too broad, matches %s", probe)
+		}
+	}
+	return canonical, re, nil
+}
+
+// globToRegexp converts a glob to its canonical form and a regular expression
+func globToRegexp(glob string) (string, string, error) {
+	glob = strings.ToLower(strings.TrimSuffix(glob, "."))
+	if !isASCII(glob) {
+		return "", "", errors.New("internationalized labels must be written in punycode")
+	}
+
+	labels := strings.Split(glob, ".")
+	if len(labels) < 2 {
+		return "", "", errors.New("must have at least two labels")
+	}
+
+	literals := 0
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		if label == "" {
+			return "", "", errors.New("empty label")
+		}
+		if label == "*" {
+			parts[i] = globLabels
+			continue
+		}
+		if strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789-*") != "" {
+			return "", "", fmt.Errorf("invalid characters in label %q", label)
+		}
+		if strings.Contains(label, "**") {
+			return "", "", fmt.Errorf("consecutive wildcards in label %q", label)
+		}
+		pieces := strings.Split(label, "*")
+		for j, piece := range pieces {
+			literals += len(piece)
+			pieces[j] = regexp.QuoteMeta(piece)
+		}
+		parts[i] = strings.Join(pieces, "[a-z0-9-]*")
+	}
+	if literals < minGlobLiterals {
+		return "", "", fmt.Errorf("needs at least %d non-wildcard characters", minGlobLiterals)
+	}
+
+	return glob, strings.Join(parts, `\.`), nil
+}
+
+// requiredLiterals counts the literal characters other than dots that every
+// match of re contains, so "/.+\.ru/" counts 2 and "/mail[0-9]+\.xyz/" 7.
+// Alternatives count their shortest branch.
+func requiredLiterals(re *syntax.Regexp) int {
+	switch re.Op {
+	case syntax.OpLiteral:
+		n := 0
+		for _, r := range re.Rune {
+			if r != '.' {
+				n++
+			}
+		}
+		return n
+	case syntax.OpConcat:
+		n := 0
+		for _, sub := range re.Sub {
+			n += requiredLiterals(sub)
+		}
+		return n
+	case syntax.OpAlternate:
+		n := -1
+		for _, sub := range re.Sub {
+			if m := requiredLiterals(sub); n < 0 || m < n {
+				n = m
+			}
+		}
+		return max(n, 0)
+	case syntax.OpCapture, syntax.OpPlus:
+		return requiredLiterals(re.Sub[0])
+	case syntax.OpRepeat:
+		if re.Min > 0 {
+			return re.Min * requiredLiterals(re.Sub[0])
+		}
+	}
+	return 0
+}
+
+// normalizeListEntry accepts a domain or a valid glob or regular expression
+// entry, for domain list sources
+func normalizeListEntry(raw string) (string, bool) {
+	if !isPattern(raw) {
+		return normalizeEntry(raw)
+	}
+	canonical, _, err := compilePattern(raw)
+	if err != nil {
+		return "", false
+	}
+	return canonical, true
+}
+
+// patternRule is a compiled list pattern and the URL it was loaded from
+type patternRule struct {
+	pattern string
+	re      *regexp.Regexp
+	origin  string
+}
+
+// patternSet matches domains against the pattern entries of the loaded
+// lists. Globs whose last label is literal are indexed by it, so a domain
+// is only tested against the globs for its top-level domain and the rest.
+type patternSet struct {
+	byTLD map[string][]patternRule
+	other []patternRule
+}
+
+// newPatternSet compiles the pattern entries among domains (entry -> origin)
+func newPatternSet(domains map[string]string) *patternSet {
+	var patterns []string
+	for entry := range domains {
+		if isPattern(entry) {
+			patterns = append(patterns, entry)
+		}
+	}
+	if len(patterns) == 0 {
+		return nil
+	}
+	sort.Strings(patterns)
+
+	set := &patternSet{byTLD: make(map[string][]patternRule)}
+	for _, pattern := range patterns {
+		_, re, err := compilePattern(pattern)
+		if err != nil {
+			// Entries were validated when parsed; skip anything else
+			continue
+		}
+		rule := patternRule{pattern: pattern, re: re, origin: domains[pattern]}
+		tld := pattern[strings.LastIndexByte(pattern, '.')+1:]
+		if isRegexEntry(pattern) || strings.Contains(tld, "*") {
+			set.other = append(set.other, rule)
+		} else {
+			set.byTLD[tld] = append(set.byTLD[tld], rule)
+		}
+	}
+	return set
+}
+
+// match returns the first candidate matched by a pattern, and the rule
+func (p *patternSet) match(candidates []string) (string, patternRule, bool) {
+	if p == nil {
+		return "", patternRule{}, false
+	}
+	for _, candidate := range candidates {
+		tld := candidate[strings.LastIndexByte(candidate, '.')+1:]
+		for _, rules := range [][]patternRule{p.byTLD[tld], p.other} {
+			for _, rule := range rules {
+				if rule.re.MatchString(candidate) {
+					return candidate, rule, true
+				}
+			}
+		}
+	}
+	return "", patternRule{}, false
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"regexp/syntax"
+	"strings"
+	"testing"
+)
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		entry         string
+		wantCanonical string
+		matches       []string
+		rejects       []string
+	}{
+		{
+			entry:         "*.tempmail.*",
+			wantCanonical: "*.tempmail.*",
+			matches:       []string{"a.tempmail.com", "a.b.tempmail.co.uk"},
+			rejects:       []string{"tempmail.com", "a.tempmailx.com"},
+		},
+		{
+			entry:         "Mail*.XYZ.",
+			wantCanonical: "mail*.xyz",
+			matches:       []string{"mail.xyz", "mail123.xyz", "mailbox.xyz"},
+			rejects:       []string{"a.mail1.xyz", "mail1.xyz.com", "gmail.xyz"},
+		},
+		{
+			entry:         `/mail[0-9]+\.xyz/`,
+			wantCanonical: `/mail[0-9]+\.xyz/`,
+			matches:       []string{"mail1.xyz", "mail123.xyz"},
+			rejects:       []string{"mail.xyz", "amail1.xyz", "mail1.xyz.com"},
+		},
+		{
+			entry:         `/^(?:temp|trash)box[0-9]*\.net$/`,
+			wantCanonical: `/^(?:temp|trash)box[0-9]*\.net$/`,
+			matches:       []string{"tempbox.net", "trashbox42.net"},
+			rejects:       []string{"box.net"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			if !isPattern(tt.entry) {
+				t.Fatalf("isPattern(%q) = false", tt.entry)
+			}
+			canonical, re, err := compilePattern(tt.entry)
+			if err != nil {
+				t.Fatalf("compilePattern() error = %v", err)
+			}
+			if canonical != tt.wantCanonical {
+				t.Errorf("canonical = %q, want %q", canonical, tt.wantCanonical)
+			}
+			for _, d := range tt.matches {
+				if !re.MatchString(d) {
+					t.Errorf("%q does not match %q", tt.entry, d)
+				}
+			}
+			for _, d := range tt.rejects {
+				if re.MatchString(d) {
+					t.Errorf("%q matches %q", tt.entry, d)
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePatternErrors(t *testing.T) {
+	tests := []struct {
+		entry   string
+		wantErr string
+	}{
+		{"*.com", "at least 4"},
+		{"*.co.*", "at least 4"},
+		{"mail*", "at least two labels"},
+		{"mail..*.com", "empty label"},
+		{"ma**il.com", "consecutive wildcards"},
+		{"mail_*.com", "invalid characters"},
+		{"bü*.de", "punycode"},
+		{`/.+\.ru/`, "at least 4"},
+		{`/[a-z]+\.(?:com|net)/`, "at least 4"},
+		{`/(?:ab|c)\.de/`, "at least 4"},
+		{`/(tempmail/`, "invalid regular expression"},
+		{`/g.*\.com/`, "too broad, matches gmail.com"},
+		{`/.*mail\.ru/`, "too broad, matches mail.ru"},
+		{`/.*(?:mail|mx)\.com/`, "too broad"},
+		{"*mail.*", "too broad"},
+		{"/" + strings.Repeat("a", maxPatternLength) + "/", "longer than"},
+		{`/tempmail[a-z]{1,600}[0-9]{1,600}\.com/`, "too complex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			_, _, err := compilePattern(tt.entry)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("compilePattern(%q) error = %v, want %q", tt.entry, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRequiredLiterals(t *testing.T) {
+	tests := []struct {
+		expr string
+		want int
+	}{
+		{`tempmail\.com`, 11},
+		{`.+\.ru`, 2},
+		{`mail[0-9]+\.xyz`, 7},
+		{`(?:temp|trash)mail\.com`, 11},
+		{`(?:tempmail)+\.com`, 11},
+		{`(?:tempmail)?\.com`, 3},
+		{`(?:ab){2}\.de`, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			re, err := syntax.Parse(tt.expr, syntax.Perl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := requiredLiterals(re); got != tt.want {
+				t.Errorf("requiredLiterals(%q) = %d, want %d", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatternSetMatch(t *testing.T) {
+	list := writeList(t, "list.txt", "tempmail.com\n*.spam.*\nmail*.xyz\n/^box[0-9]+\\.example\\.net$/\n")
+	s := newTestService(t, Options{ListURLs: []string{list}})
+
+	tests := []struct {
+		email       string
+		wantMatched string
+		wantRule    string
+	}{
+		{"a@tempmail.com", "tempmail.com", ""},
+		{"a@x.spam.io", "x.spam.io", "*.spam.*"},
+		{"a@deep.x.spam.co.uk", "deep.x.spam.co.uk", "*.spam.*"},
+		{"a@mail42.xyz", "mail42.xyz", "mail*.xyz"},
+		{"a@sub.mail42.xyz", "mail42.xyz", "mail*.xyz"},
+		{"a@box7.example.net", "box7.example.net", "/^box[0-9]+\\.example\\.net$/"},
+		{"a@example.net", "", ""},
+		{"a@spam.io", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			v, err := s.Check(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.MatchedDomain != tt.wantMatched || v.MatchedRule != tt.wantRule || v.Disposable != (tt.wantMatched != "") {
+				t.Errorf("Check(%q) = matched %q, rule %q, disposable %v; want %q, %q",
+					tt.email, v.MatchedDomain, v.MatchedRule, v.Disposable, tt.wantMatched, tt.wantRule)
+			}
+		})
+	}
+}
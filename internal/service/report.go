@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// maxRefreshHistory is the number of refresh reports kept in memory
+const maxRefreshHistory = 50
+
+// Refresh triggers recorded in reports
+const (
+	TriggerStartup    = "startup"
+	TriggerInterval   = "interval"
+	TriggerFileChange = "file_change"
+	TriggerManual     = "manual"
+)
+
+// Source outcomes recorded in reports
+const (
+	OutcomeUpdated     = "updated"
+	OutcomeNotModified = "not_modified"
+	OutcomeFailed      = "failed"
+	OutcomeSkipped     = "skipped"
+)
+
+// RefreshReport describes the outcome of a single refresh
+type RefreshReport struct {
+	Trigger     string          `json:"trigger"`
+	Mode        string          `json:"mode"`
+	StartedAt   time.Time       `json:"started_at"`
+	DurationMS  int64           `json:"duration_ms"`
+	Success     bool            `json:"success"`
+	Error       string          `json:"error,omitempty"`
+	DomainCount int             `json:"domain_count"`
+	Sources     []SourceOutcome `json:"sources"`
+}
+
+// SourceOutcome is the result of fetching one source during a refresh
+type SourceOutcome struct {
+	URL string `json:"url"`
+	// List is the host list kind (HostListMX, HostListNS or
+	// HostListFreeMail) for host list sources, empty for domain list sources
+	List        string `json:"list,omitempty"`
+	Outcome     string `json:"outcome"`
+	DomainCount int    `json:"domain_count,omitempty"`
+	Error       string `json:"error,omitempty"`
+	DurationMS  int64  `json:"duration_ms"`
+}
+
+// addOutcome records the result of fetching url
+func (r *RefreshReport) addOutcome(url string, res fetchResult, duration time.Duration) {
+	outcome := SourceOutcome{
+		URL:        url,
+		DurationMS: duration.Milliseconds(),
+	}
+	switch {
+	case res.err != nil:
+		outcome.Outcome = OutcomeFailed
+		outcome.Error = res.err.Error()
+	case res.status == http.StatusNotModified:
+		outcome.Outcome = OutcomeNotModified
+	default:
+		outcome.Outcome = OutcomeUpdated
+		outcome.DomainCount = len(res.domains)
+	}
+	r.Sources = append(r.Sources, outcome)
+}
+
+// addSkipped records sources left untouched after an earlier one succeeded
+func (r *RefreshReport) addSkipped(urls []string) {
+	for _, url := range urls {
+		r.Sources = append(r.Sources, SourceOutcome{URL: url, Outcome: OutcomeSkipped})
+	}
+}
+
+// Refresh runs a refresh immediately and returns its report
+func (s *DisposableEmailService) Refresh() (RefreshReport, error) {
+	return s.refresh(TriggerManual)
+}
+
+// recordHistory appends a report, dropping the oldest beyond maxRefreshHistory
+func (s *DisposableEmailService) recordHistory(report RefreshReport) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	s.history = append(s.history, report)
+	if len(s.history) > maxRefreshHistory {
+		s.history = s.history[len(s.history)-maxRefreshHistory:]
+	}
+}
+
+// RefreshHistory returns the most recent refresh reports, newest first
+func (s *DisposableEmailService) RefreshHistory() []RefreshReport {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	history := make([]RefreshReport, len(s.history))
+	for i, report := range s.history {
+		history[len(s.history)-1-i] = report
+	}
+	return history
+}
+
+// Lookup evaluates a bare domain the same way Check evaluates an email
+// address, but ignores the failure policy
+func (s *DisposableEmailService) Lookup(d string) (domain.Verdict, error) {
+	d, err := canonicalDomain(d)
+	if err != nil || d == "" {
+		return domain.Verdict{}, domain.ErrInvalidEmail
+	}
+
+	verdict, err := s.evaluate(domain.Verdict{Domain: d, DomainUnicode: unicodeDomain(d)}, true)
+	if errors.Is(err, domain.ErrListUnavailable) {
+		err = nil
+	}
+	verdict.FreeMail, verdict.FreeMailOrigin, _ = s.matchFreeMail(d)
+	return verdict, err
+}
+
+// DomainEntry is a loaded domain and the source that listed it
+type DomainEntry struct {
+	Domain string `json:"domain"`
+	Source string `json:"source"`
+}
+
+// Domains returns a page of the loaded domains in sorted order and the total
+// number of domains
+func (s *DisposableEmailService) Domains(offset, limit int) ([]DomainEntry, int) {
+	s.mu.RLock()
+	domains, gen := s.domains, s.domainsGen
+	s.mu.RUnlock()
+
+	sorted := s.sortedView(domains, gen)
+	total := len(sorted)
+	if offset < 0 || offset >= total {
+		return []DomainEntry{}, total
+	}
+	end := min(offset+limit, total)
+
+	page := make([]DomainEntry, 0, end-offset)
+	for _, d := range sorted[offset:end] {
+		page = append(page, DomainEntry{Domain: d, Source: domains[d]})
+	}
+	return page, total
+}
+
+// sortedView returns the sorted keys of domains, the list of generation gen.
+// The view is built once per generation, under sortedMu only.
+func (s *DisposableEmailService) sortedView(domains map[string]string, gen uint64) []string {
+	s.sortedMu.Lock()
+	defer s.sortedMu.Unlock()
+
+	if s.sortedDomains == nil || s.sortedGen != gen {
+		sorted := make([]string, 0, len(domains))
+		for d := range domains {
+			sorted = append(sorted, d)
+		}
+		sort.Strings(sorted)
+		s.sortedDomains, s.sortedGen = sorted, gen
+	}
+	return s.sortedDomains
+}
+
+// setDomainsLocked replaces the loaded list and its patterns, built with
+// newPatternSet before taking the lock, which invalidates the sorted view.
+// Caller must hold s.mu for writing.
+func (s *DisposableEmailService) setDomainsLocked(domains map[string]string, patterns *patternSet) {
+	s.domains = domains
+	s.patterns = patterns
+	s.domainsGen++
+}
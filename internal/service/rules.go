@@ -0,0 +1,272 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rule actions
+const (
+	RuleAllow = "allow"
+	RuleDeny  = "deny"
+)
+
+// ErrInvalidRule is returned when a rule fails validation
+var ErrInvalidRule = errors.New("invalid rule")
+
+// ErrRuleShadowed is returned when a new deny rule would never apply because
+// an allow rule for a parent domain wins over it, or when a new allow rule
+// would disable the deny rules for its subdomains
+var ErrRuleShadowed = errors.New("rule shadowed")
+
+// rulesVersion is the version of the rule file layout
+const rulesVersion = 1
+
+// Rule is a custom allow/deny entry managed at runtime
+type Rule struct {
+	Domain    string     `json:"domain"`
+	Action    string     `json:"action"`
+	Author    string     `json:"author"`
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// expired reports whether the rule no longer applies at now
+func (r Rule) expired(now time.Time) bool {
+	return r.ExpiresAt != nil && !now.Before(*r.ExpiresAt)
+}
+
+// RuleStore keeps custom rules in memory and persists every change to a
+// JSON file, so rules survive restarts. An empty path keeps rules in memory only.
+type RuleStore struct {
+	path string
+
+	mu    sync.RWMutex
+	rules map[string]Rule
+}
+
+// rulesFile is the on-disk layout of the rule store
+type rulesFile struct {
+	Version int    `json:"version"`
+	Rules   []Rule `json:"rules"`
+}
+
+// OpenRuleStore loads the rules persisted at path. A missing file starts an
+// empty store.
+func OpenRuleStore(path string) (*RuleStore, error) {
+	store := &RuleStore{
+		path:  path,
+		rules: make(map[string]Rule),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode rules: %w", err)
+	}
+	if file.Version != rulesVersion {
+		return nil, fmt.Errorf("unsupported rules version %d", file.Version)
+	}
+	for i, rule := range file.Rules {
+		rule, err := rule.normalize()
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if _, dup := store.rules[rule.Domain]; dup {
+			return nil, fmt.Errorf("rule %d: %w: duplicate rule for %s", i+1, ErrInvalidRule, rule.Domain)
+		}
+		store.rules[rule.Domain] = rule
+	}
+
+	return store, nil
+}
+
+// normalize returns the rule with its domain in canonical form, or an
+// ErrInvalidRule error when the domain or action is invalid
+func (r Rule) normalize() (Rule, error) {
+	domain, ok := normalizeEntry(r.Domain)
+	if !ok {
+		return Rule{}, fmt.Errorf("%w: domain %q", ErrInvalidRule, r.Domain)
+	}
+	if r.Action != RuleAllow && r.Action != RuleDeny {
+		return Rule{}, fmt.Errorf("%w: action must be %q or %q", ErrInvalidRule, RuleAllow, RuleDeny)
+	}
+	r.Domain = domain
+	return r, nil
+}
+
+// List returns all rules that have not expired, sorted by domain
+func (s *RuleStore) List() []Rule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	rules := make([]Rule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		if !rule.expired(now) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Domain < rules[j].Domain })
+	return rules
+}
+
+// Get returns the rule for a domain unless it has expired
+func (s *RuleStore) Get(domain string) (Rule, bool) {
+	if d, ok := normalizeEntry(domain); ok {
+		domain = d
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rule, ok := s.rules[domain]
+	if !ok || rule.expired(time.Now()) {
+		return Rule{}, false
+	}
+	return rule, true
+}
+
+// Put validates and creates or replaces the rule for rule.Domain, keeping the
+// original creation time on update. Deny rules under an allowed parent
+// domain, and allow rules over denied subdomains, are refused with
+// ErrRuleShadowed.
+func (s *RuleStore) Put(rule Rule) (Rule, error) {
+	rule, err := rule.normalize()
+	if err != nil {
+		return Rule{}, err
+	}
+	domain := rule.Domain
+	if rule.Author == "" {
+		return Rule{}, fmt.Errorf("%w: author is required", ErrInvalidRule)
+	}
+	now := time.Now()
+	if rule.expired(now) {
+		return Rule{}, fmt.Errorf("%w: expires_at is in the past", ErrInvalidRule)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch rule.Action {
+	case RuleDeny:
+		for _, parent := range domainCandidates(domain)[1:] {
+			if allow, ok := s.rules[parent]; ok && allow.Action == RuleAllow && !allow.expired(now) {
+				return Rule{}, fmt.Errorf("%w: allow rule for %s wins over a deny rule for %s", ErrRuleShadowed, parent, domain)
+			}
+		}
+	case RuleAllow:
+		var denied []string
+		for child, deny := range s.rules {
+			if deny.Action == RuleDeny && !deny.expired(now) && strings.HasSuffix(child, "."+domain) {
+				denied = append(denied, child)
+			}
+		}
+		if len(denied) > 0 {
+			sort.Strings(denied)
+			return Rule{}, fmt.Errorf("%w: allow rule for %s would win over deny rules for %s",
+				ErrRuleShadowed, domain, strings.Join(denied, ", "))
+		}
+	}
+
+	rule.CreatedAt = now
+	if existing, ok := s.rules[domain]; ok && !existing.expired(now) {
+		rule.CreatedAt = existing.CreatedAt
+	}
+	rule.UpdatedAt = now
+
+	previous, existed := s.rules[domain]
+	s.rules[domain] = rule
+	if err := s.persistLocked(); err != nil {
+		if existed {
+			s.rules[domain] = previous
+		} else {
+			delete(s.rules, domain)
+		}
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+// Delete removes the rule for a domain; it reports whether a rule existed
+func (s *RuleStore) Delete(domain string) (bool, error) {
+	if d, ok := normalizeEntry(domain); ok {
+		domain = d
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous, ok := s.rules[domain]
+	if !ok {
+		return false, nil
+	}
+	delete(s.rules, domain)
+	if err := s.persistLocked(); err != nil {
+		s.rules[domain] = previous
+		return false, err
+	}
+	return !previous.expired(time.Now()), nil
+}
+
+// match returns the first candidate with an active rule for action
+func (s *RuleStore) match(candidates []string, action string) (string, Rule, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	for _, candidate := range candidates {
+		if rule, ok := s.rules[candidate]; ok && rule.Action == action && !rule.expired(now) {
+			return candidate, rule, true
+		}
+	}
+	return "", Rule{}, false
+}
+
+// persistLocked writes all rules to disk, dropping expired ones.
+// Caller must hold s.mu for writing.
+func (s *RuleStore) persistLocked() error {
+	now := time.Now()
+	for domain, rule := range s.rules {
+		if rule.expired(now) {
+			delete(s.rules, domain)
+		}
+	}
+	if s.path == "" {
+		return nil
+	}
+
+	file := rulesFile{Version: rulesVersion, Rules: make([]Rule, 0, len(s.rules))}
+	for _, rule := range s.rules {
+		file.Rules = append(file.Rules, rule)
+	}
+	sort.Slice(file.Rules, func(i, j int) bool { return file.Rules[i].Domain < file.Rules[j].Domain })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode rules: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return fmt.Errorf("failed to persist rules: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+func TestRulePrecedence(t *testing.T) {
+	rules, _ := OpenRuleStore("")
+	for _, rule := range []Rule{
+		{Domain: "customallow.com", Action: RuleAllow, Author: "ops"},
+		{Domain: "staticallow.com", Action: RuleDeny, Author: "ops"},
+		{Domain: "both.com", Action: RuleAllow, Author: "ops"},
+	} {
+		if _, err := rules.Put(rule); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	list := writeList(t, "list.txt", "customallow.com\nstaticallow.com\nlisted.com\nstaticdeny.com\n")
+	s := newTestService(t, Options{
+		ListURLs: []string{list},
+		Rules:    rules,
+		Overrides: Overrides{
+			AllowDomains: []string{"staticallow.com", "listed.com"},
+			DenyDomains:  []string{"staticdeny.com", "listed.com", "other.com"},
+		},
+	})
+
+	tests := []struct {
+		email          string
+		wantDisposable bool
+		wantSource     string
+	}{
+		// Custom allow beats the list
+		{"a@customallow.com", false, domain.SourceCustomAllow},
+		// Custom deny beats a static allow entry
+		{"a@staticallow.com", true, domain.SourceCustomDeny},
+		// Static allow beats static deny and the list
+		{"a@listed.com", false, domain.SourceAllowlist},
+		{"a@staticdeny.com", true, domain.SourceDenylist},
+		{"a@other.com", true, domain.SourceDenylist},
+		{"a@x.both.com", false, domain.SourceCustomAllow},
+		{"a@unlisted.com", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			v, err := s.Check(tt.email)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Disposable != tt.wantDisposable || v.Source != tt.wantSource {
+				t.Errorf("Check(%q) = disposable %v, source %q; want %v, %q",
+					tt.email, v.Disposable, v.Source, tt.wantDisposable, tt.wantSource)
+			}
+		})
+	}
+}
+
+func TestRuleStorePut(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr error
+	}{
+		{"allow", Rule{Domain: "Example.COM", Action: RuleAllow, Author: "ops"}, nil},
+		{"invalid domain", Rule{Domain: "not a domain", Action: RuleDeny, Author: "ops"}, ErrInvalidRule},
+		{"invalid action", Rule{Domain: "a.com", Action: "block", Author: "ops"}, ErrInvalidRule},
+		{"missing author", Rule{Domain: "a.com", Action: RuleDeny}, ErrInvalidRule},
+		{"expired", Rule{Domain: "a.com", Action: RuleDeny, Author: "ops", ExpiresAt: &past}, ErrInvalidRule},
+		{"shadowed deny", Rule{Domain: "mail.example.com", Action: RuleDeny, Author: "ops"}, ErrRuleShadowed},
+		{"allow under allow", Rule{Domain: "mail.example.com", Action: RuleAllow, Author: "ops"}, nil},
+		{"deny", Rule{Domain: "a.tempmail.net", Action: RuleDeny, Author: "ops"}, nil},
+		{"allow over a denied subdomain", Rule{Domain: "tempmail.net", Action: RuleAllow, Author: "ops"}, ErrRuleShadowed},
+		{"allow replacing the deny rule", Rule{Domain: "a.tempmail.net", Action: RuleAllow, Author: "ops"}, nil},
+		{"allow once the subdomain is allowed", Rule{Domain: "tempmail.net", Action: RuleAllow, Author: "ops"}, nil},
+		{"allow over a lookalike domain", Rule{Domain: "mail.net", Action: RuleAllow, Author: "ops"}, nil},
+	}
+
+	store, _ := OpenRuleStore("")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := store.Put(tt.rule)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Put() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if rule, ok := store.Get("example.com"); !ok || rule.Domain != "example.com" {
+		t.Errorf("Get(example.com) = %+v, %v; want the normalized rule", rule, ok)
+	}
+}
+
+func TestRuleStoreExpiry(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	path := filepath.Join(t.TempDir(), "rules.json")
+	data := `{"version": 1, "rules": [
+		{"domain": "expired.com", "action": "deny", "author": "ops", "expires_at": "` + past.Format(time.RFC3339Nano) + `"},
+		{"domain": "active.com", "action": "deny", "author": "ops", "expires_at": "` + future.Format(time.RFC3339Nano) + `"},
+		{"domain": "expiredallow.com", "action": "allow", "author": "ops", "expires_at": "` + past.Format(time.RFC3339Nano) + `"}
+	]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := OpenRuleStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		domain string
+		active bool
+	}{
+		{"expired.com", false},
+		{"active.com", true},
+		{"expiredallow.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if _, ok := store.Get(tt.domain); ok != tt.active {
+				t.Errorf("Get(%q) found = %v, want %v", tt.domain, ok, tt.active)
+			}
+			_, _, matched := store.match([]string{tt.domain}, RuleDeny)
+			if matched != tt.active {
+				t.Errorf("match(%q) = %v, want %v", tt.domain, matched, tt.active)
+			}
+		})
+	}
+	if got := len(store.List()); got != 1 {
+		t.Errorf("len(List()) = %d, want 1", got)
+	}
+
+	// An expired allow rule no longer shadows deny rules below it
+	if _, err := store.Put(Rule{Domain: "a.expiredallow.com", Action: RuleDeny, Author: "ops"}); err != nil {
+		t.Errorf("Put() under an expired allow rule: %v", err)
+	}
+}
+
+func TestRuleStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	store, err := OpenRuleStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := store.Put(Rule{Domain: "a.com", Action: RuleDeny, Author: "ops", Reason: "abuse"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(Rule{Domain: "b.com", Action: RuleAllow, Author: "ops"}); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := store.Put(Rule{Domain: "a.com", Action: RuleDeny, Author: "sec"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("update changed CreatedAt from %v to %v", created.CreatedAt, updated.CreatedAt)
+	}
+	if ok, err := store.Delete("b.com"); !ok || err != nil {
+		t.Errorf("Delete(b.com) = %v, %v; want true, nil", ok, err)
+	}
+
+	reopened, err := OpenRuleStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := reopened.List()
+	if len(rules) != 1 || rules[0].Domain != "a.com" || rules[0].Author != "sec" {
+		t.Errorf("reopened rules = %+v, want only the updated a.com rule", rules)
+	}
+}
+
+func TestOpenRuleStoreNormalizes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	data := `{"version": 1, "rules": [{"domain": "Bücher.DE.", "action": "deny", "author": "ops"}]}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := OpenRuleStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := store.match([]string{"xn--bcher-kva.de"}, RuleDeny); !ok {
+		t.Error("rule not found under its canonical domain")
+	}
+}
+
+func TestOpenRuleStoreErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"malformed", `{"version": 1, "rules": [`, "failed to decode"},
+		{"missing version", `{"rules": []}`, "unsupported rules version 0"},
+		{"future version", `{"version": 2, "rules": []}`, "unsupported rules version 2"},
+		{"invalid domain", `{"version": 1, "rules": [{"domain": "not a domain", "action": "deny"}]}`, "rule 1: invalid rule"},
+		{"invalid action", `{"version": 1, "rules": [{"domain": "a.com", "action": "block"}]}`, "action must be"},
+		{"duplicate", `{"version": 1, "rules": [{"domain": "a.com", "action": "deny"}, {"domain": "A.com", "action": "allow"}]}`, "rule 2: invalid rule: duplicate rule for a.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := OpenRuleStore(path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("OpenRuleStore() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
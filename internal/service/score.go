@@ -0,0 +1,214 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+// ScoreOptions configures risk scoring. When enabled, every verdict carries a
+// domain.Risk combining the weighted signals that fired for the address.
+type ScoreOptions struct {
+	Enabled bool
+	// Weights overrides domain.DefaultSignalWeights by signal name; a weight
+	// of 0 disables the signal
+	Weights map[string]int
+	// FlagThreshold and BlockThreshold are the scores at which an address is
+	// flagged or blocked
+	FlagThreshold  int
+	BlockThreshold int
+	// NewDomainWindow is how long a domain counts as new after it was first
+	// seen; 0 disables the new_domain signal
+	NewDomainWindow time.Duration
+}
+
+// maxTrackedDomains bounds the memory used to remember seen domains. Once
+// reached, domains not seen for a window are forgotten, then the least
+// recently seen ones.
+const maxTrackedDomains = 100000
+
+// scorer combines the signals of an address into a risk score
+type scorer struct {
+	weights map[string]int
+	flag    int
+	block   int
+	// seen remembers when domains were first seen; nil disables new_domain
+	seen *domainTracker
+}
+
+// newScorer returns nil when scoring is disabled
+func newScorer(opts ScoreOptions) *scorer {
+	if !opts.Enabled {
+		return nil
+	}
+	weights := make(map[string]int, len(domain.DefaultSignalWeights))
+	for signal, weight := range domain.DefaultSignalWeights {
+		weights[signal] = weight
+	}
+	for signal, weight := range opts.Weights {
+		weights[signal] = weight
+	}
+
+	sc := &scorer{weights: weights, flag: opts.FlagThreshold, block: opts.BlockThreshold}
+	if opts.NewDomainWindow > 0 && weights[domain.SignalNewDomain] != 0 {
+		sc.seen = newDomainTracker(opts.NewDomainWindow)
+	}
+	return sc
+}
+
+// score assesses an address from its verdict. Allow and deny entries decide
+// regardless of the score. Audit traffic (see WithAudit) does not record
+// sightings for new_domain.
+func (sc *scorer) score(addr Address, v domain.Verdict, audit bool) *domain.Risk {
+	if sc == nil {
+		return nil
+	}
+
+	switch v.Source {
+	case domain.SourceAllowlist, domain.SourceCustomAllow:
+		return &domain.Risk{Decision: domain.RiskAllow, Signals: []domain.Signal{}, Policy: true}
+	case domain.SourceDenylist, domain.SourceCustomDeny:
+		return &domain.Risk{Decision: domain.RiskBlock, Signals: []domain.Signal{}, Policy: true}
+	}
+
+	risk := &domain.Risk{Signals: []domain.Signal{}}
+	add := func(name, detail string) {
+		weight := sc.weights[name]
+		if weight == 0 {
+			return
+		}
+		risk.Signals = append(risk.Signals, domain.Signal{Name: name, Weight: weight, Detail: detail})
+		risk.Score += weight
+	}
+
+	switch {
+	case v.Disposable && (v.Source == domain.SourceMX || v.Source == domain.SourceNS):
+		host := v.MXHost
+		if v.Source == domain.SourceNS {
+			host = v.NSHost
+		}
+		add(domain.SignalHostHit, v.Source+" "+host)
+	case v.Disposable && v.MatchedDomain == v.Domain:
+		add(domain.SignalListHit, firstNonEmpty(v.MatchedRule, v.MatchedDomain))
+	case v.Disposable:
+		add(domain.SignalSubdomainHit, firstNonEmpty(v.MatchedRule, v.MatchedDomain))
+	}
+
+	if v.NoMX {
+		add(domain.SignalNoMX, firstNonEmpty(v.Undeliverable, domain.UndeliverableNoMX))
+	}
+
+	if !addr.IPLiteral && sc.seen != nil {
+		registrable, err := publicsuffix.EffectiveTLDPlusOne(v.Domain)
+		if err != nil {
+			registrable = v.Domain
+		}
+		if sc.seen.observe(registrable, !audit) {
+			add(domain.SignalNewDomain, registrable)
+		}
+	}
+	if v.FreeMail != "" {
+		add(domain.SignalFreeMail, v.FreeMail)
+	}
+
+	if v.RoleAccount != "" {
+		add(domain.SignalRoleAccount, v.RoleAccount)
+	}
+	if detail := firstNonEmpty(v.LocalPartPattern, v.RandomLocalPart); detail != "" {
+		add(domain.SignalSuspiciousLocalPart, detail)
+	}
+
+	switch {
+	case risk.Score >= sc.block:
+		risk.Decision = domain.RiskBlock
+	case risk.Score >= sc.flag:
+		risk.Decision = domain.RiskFlag
+	default:
+		risk.Decision = domain.RiskAllow
+	}
+	return risk
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// domainTracker remembers when each domain was first seen. A domain is new
+// while it was first seen less than window ago; during the first window
+// after startup nothing is new, since every domain is seen for the first time.
+type domainTracker struct {
+	mu        sync.Mutex
+	window    time.Duration
+	startedAt time.Time
+	seen      map[string]sighting
+}
+
+// sighting records when a domain was first and last seen
+type sighting struct {
+	first, last time.Time
+}
+
+func newDomainTracker(window time.Duration) *domainTracker {
+	return &domainTracker{
+		window:    window,
+		startedAt: time.Now(),
+		seen:      make(map[string]sighting),
+	}
+}
+
+// observe reports whether d is new. With record set the sighting is
+// remembered; without it unknown domains are not new.
+func (t *domainTracker) observe(d string, record bool) bool {
+	now := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sg, ok := t.seen[d]
+	switch {
+	case !ok && !record:
+		return false
+	case !ok:
+		if len(t.seen) >= maxTrackedDomains {
+			t.evictLocked(now)
+		}
+		sg.first = now
+	}
+	if record {
+		sg.last = now
+		t.seen[d] = sg
+	}
+	return sg.first.Sub(t.startedAt) >= t.window && now.Sub(sg.first) < t.window
+}
+
+// evictLocked forgets the domains not seen for a window and, if that frees
+// too little, the least recently seen tenth. Caller must hold t.mu.
+func (t *domainTracker) evictLocked(now time.Time) {
+	for d, sg := range t.seen {
+		if now.Sub(sg.last) >= t.window {
+			delete(t.seen, d)
+		}
+	}
+	if len(t.seen) < maxTrackedDomains*9/10 {
+		return
+	}
+
+	domains := make([]string, 0, len(t.seen))
+	for d := range t.seen {
+		domains = append(domains, d)
+	}
+	sort.Slice(domains, func(i, j int) bool { return t.seen[domains[i]].last.Before(t.seen[domains[j]].last) })
+	for _, d := range domains[:len(domains)-maxTrackedDomains*9/10] {
+		delete(t.seen, d)
+	}
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ilyasaftr/ory-kratos-disposable/internal/domain"
+)
+
+func TestScorerThresholds(t *testing.T) {
+	sc := newScorer(ScoreOptions{
+		Enabled:        true,
+		Weights:        map[string]int{domain.SignalFreeMail: 0},
+		FlagThreshold:  30,
+		BlockThreshold: 90,
+	})
+
+	tests := []struct {
+		name         string
+		verdict      domain.Verdict
+		wantScore    int
+		wantDecision string
+		wantSignals  []string
+	}{
+		{
+			name:         "clean",
+			verdict:      domain.Verdict{Domain: "example.com"},
+			wantDecision: domain.RiskAllow,
+		},
+		{
+			name:         "list hit",
+			verdict:      domain.Verdict{Domain: "tempmail.com", Disposable: true, Source: domain.SourceList, MatchedDomain: "tempmail.com"},
+			wantScore:    100,
+			wantDecision: domain.RiskBlock,
+			wantSignals:  []string{domain.SignalListHit},
+		},
+		{
+			name:         "subdomain hit",
+			verdict:      domain.Verdict{Domain: "mx.tempmail.com", Disposable: true, Source: domain.SourceList, MatchedDomain: "tempmail.com"},
+			wantScore:    80,
+			wantDecision: domain.RiskFlag,
+			wantSignals:  []string{domain.SignalSubdomainHit},
+		},
+		{
+			name:         "host hit",
+			verdict:      domain.Verdict{Domain: "x.com", Disposable: true, Source: domain.SourceMX, MXHost: "in.mailinator.com"},
+			wantScore:    80,
+			wantDecision: domain.RiskFlag,
+			wantSignals:  []string{domain.SignalHostHit},
+		},
+		{
+			name:         "role account at the flag threshold",
+			verdict:      domain.Verdict{Domain: "example.com", RoleAccount: "admin"},
+			wantScore:    30,
+			wantDecision: domain.RiskFlag,
+			wantSignals:  []string{domain.SignalRoleAccount},
+		},
+		{
+			name:         "no mx and random local part reach the block threshold",
+			verdict:      domain.Verdict{Domain: "example.com", NoMX: true, Undeliverable: domain.UndeliverableNoMX, RandomLocalPart: "random characters"},
+			wantScore:    90,
+			wantDecision: domain.RiskBlock,
+			wantSignals:  []string{domain.SignalNoMX, domain.SignalSuspiciousLocalPart},
+		},
+		{
+			name:         "disabled signal",
+			verdict:      domain.Verdict{Domain: "gmail.com", FreeMail: "gmail.com"},
+			wantDecision: domain.RiskAllow,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			risk := sc.score(Address{Domain: tt.verdict.Domain}, tt.verdict, false)
+			if risk.Score != tt.wantScore || risk.Decision != tt.wantDecision {
+				t.Errorf("score = %d/%s, want %d/%s", risk.Score, risk.Decision, tt.wantScore, tt.wantDecision)
+			}
+			var signals []string
+			for _, s := range risk.Signals {
+				signals = append(signals, s.Name)
+			}
+			if fmt.Sprint(signals) != fmt.Sprint(tt.wantSignals) {
+				t.Errorf("signals = %v, want %v", signals, tt.wantSignals)
+			}
+		})
+	}
+}
+
+func TestScorerPolicySources(t *testing.T) {
+	sc := newScorer(ScoreOptions{Enabled: true, FlagThreshold: 1, BlockThreshold: 1})
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{domain.SourceAllowlist, domain.RiskAllow},
+		{domain.SourceCustomAllow, domain.RiskAllow},
+		{domain.SourceDenylist, domain.RiskBlock},
+		{domain.SourceCustomDeny, domain.RiskBlock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			v := domain.Verdict{Domain: "example.com", Source: tt.source, RoleAccount: "admin"}
+			risk := sc.score(Address{Domain: v.Domain}, v, false)
+			if risk.Decision != tt.want || !risk.Policy || risk.Score != 0 {
+				t.Errorf("risk = %+v, want a policy %s decision", risk, tt.want)
+			}
+		})
+	}
+
+	if risk := newScorer(ScoreOptions{}).score(Address{}, domain.Verdict{}, false); risk != nil {
+		t.Errorf("disabled scorer returned %+v", risk)
+	}
+}
+
+func TestDomainTracker(t *testing.T) {
+	tr := newDomainTracker(time.Hour)
+	if tr.observe("a.com", true) {
+		t.Error("domain reported new during the first window after startup")
+	}
+
+	tr.startedAt = time.Now().Add(-2 * time.Hour)
+	tr.seen["old.com"] = sighting{first: tr.startedAt, last: tr.startedAt}
+
+	tests := []struct {
+		name   string
+		domain string
+		record bool
+		want   bool
+	}{
+		{"first sighting", "new.com", true, true},
+		{"still within the window", "new.com", true, true},
+		{"seen long ago", "old.com", true, false},
+		{"audit sighting of an unknown domain", "audit.com", false, false},
+		{"audit sighting is not recorded", "audit.com", false, false},
+		{"audit sighting of a new domain", "new.com", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tr.observe(tt.domain, tt.record); got != tt.want {
+				t.Errorf("observe(%q, %v) = %v, want %v", tt.domain, tt.record, got, tt.want)
+			}
+		})
+	}
+	if _, ok := tr.seen["audit.com"]; ok {
+		t.Error("audit sighting was recorded")
+	}
+}
+
+func TestDomainTrackerEviction(t *testing.T) {
+	now := time.Now()
+
+	t.Run("stale domains", func(t *testing.T) {
+		tr := newDomainTracker(time.Hour)
+		for i := range maxTrackedDomains {
+			last := now.Add(-time.Minute)
+			if i%2 == 0 {
+				last = now.Add(-2 * time.Hour)
+			}
+			tr.seen[fmt.Sprintf("d%d.com", i)] = sighting{first: last, last: last}
+		}
+		tr.observe("fresh.com", true)
+		if got, want := len(tr.seen), maxTrackedDomains/2+1; got != want {
+			t.Errorf("tracked %d domains, want %d", got, want)
+		}
+		if _, ok := tr.seen["d0.com"]; ok {
+			t.Error("stale domain kept")
+		}
+	})
+
+	t.Run("least recently seen", func(t *testing.T) {
+		tr := newDomainTracker(time.Hour)
+		for i := range maxTrackedDomains {
+			last := now.Add(-time.Duration(maxTrackedDomains-i) * time.Millisecond)
+			tr.seen[fmt.Sprintf("d%d.com", i)] = sighting{first: last, last: last}
+		}
+		tr.observe("fresh.com", true)
+		if got, want := len(tr.seen), maxTrackedDomains*9/10+1; got != want {
+			t.Errorf("tracked %d domains, want %d", got, want)
+		}
+		if _, ok := tr.seen["d0.com"]; ok {
+			t.Error("least recently seen domain kept")
+		}
+		if _, ok := tr.seen[fmt.Sprintf("d%d.com", maxTrackedDomains-1)]; !ok {
+			t.Error("most recently seen domain evicted")
+		}
+	})
+}
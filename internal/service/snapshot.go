@@ -0,0 +1,201 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// snapshotVersion is bumped whenever the snapshot layout changes
+const snapshotVersion = 1
+
+// snapshot is the on-disk copy of the last good list state, used to warm
+// start the service before the first network attempt
+type snapshot struct {
+	Version      int                       `json:"version"`
+	SavedAt      time.Time                 `json:"saved_at"`
+	ActiveSource string                    `json:"active_source,omitempty"`
+	Sources      map[string]snapshotSource `json:"sources"`
+	// HostLists holds the host list sources keyed by list kind, then URL
+	HostLists map[string]map[string]snapshotSource `json:"host_lists,omitempty"`
+}
+
+// snapshotSource is the last good copy of a single source
+type snapshotSource struct {
+	ETag      string    `json:"etag,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Domains   []string  `json:"domains"`
+}
+
+// saveSnapshot writes the current source state to disk atomically
+func (s *DisposableEmailService) saveSnapshot() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+
+	s.mu.RLock()
+	snap := snapshot{
+		Version:      snapshotVersion,
+		SavedAt:      time.Now(),
+		ActiveSource: s.activeSource,
+		Sources:      snapshotSources(s.sources),
+	}
+	for _, list := range s.hostLists() {
+		if snap.HostLists == nil {
+			snap.HostLists = make(map[string]map[string]snapshotSource)
+		}
+		snap.HostLists[list.kind] = snapshotSources(list.sources)
+	}
+	s.mu.RUnlock()
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	return writeFileAtomic(s.snapshotPath, data)
+}
+
+// snapshotSources copies the last good copy of every source with data.
+// Caller must hold s.mu.
+func snapshotSources(sources map[string]*sourceState) map[string]snapshotSource {
+	saved := make(map[string]snapshotSource, len(sources))
+	for url, st := range sources {
+		if st.domains == nil {
+			continue
+		}
+		domains := make([]string, 0, len(st.domains))
+		for d := range st.domains {
+			domains = append(domains, d)
+		}
+		sort.Strings(domains)
+		saved[url] = snapshotSource{
+			ETag:      st.etag,
+			FetchedAt: st.lastSuccess,
+			Domains:   domains,
+		}
+	}
+	return saved
+}
+
+// restoreSources loads saved copies into the configured sources and returns
+// the newest fetch time. Caller must hold s.mu for writing.
+func restoreSources(sources map[string]*sourceState, saved map[string]snapshotSource) time.Time {
+	var newest time.Time
+	for url, src := range saved {
+		st, ok := sources[url]
+		if !ok || len(src.Domains) == 0 {
+			continue
+		}
+		st.etag = src.ETag
+		st.lastSuccess = src.FetchedAt
+		st.domains = make(map[string]bool, len(src.Domains))
+		for _, d := range src.Domains {
+			st.domains[d] = true
+		}
+		if src.FetchedAt.After(newest) {
+			newest = src.FetchedAt
+		}
+	}
+	return newest
+}
+
+// loadSnapshot restores source state from disk. Sources that are no longer
+// configured are ignored. A missing snapshot is not an error.
+func (s *DisposableEmailService) loadSnapshot() error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.snapshotPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snap.Version)
+	}
+
+	s.mu.Lock()
+	for kind, saved := range snap.HostLists {
+		list, err := s.hostListByKind(kind)
+		if err != nil {
+			continue
+		}
+		restoreSources(list.sources, saved)
+		list.rebuildLocked()
+	}
+
+	newest := restoreSources(s.sources, snap.Sources)
+
+	var (
+		domains map[string]string
+		active  string
+	)
+	if s.listMode == ListModeUnion {
+		domains = s.mergeSourcesLocked()
+	} else if st, ok := s.sources[snap.ActiveSource]; ok && st.domains != nil {
+		domains = withOrigin(st.domains, snap.ActiveSource)
+		active = snap.ActiveSource
+	}
+	s.mu.Unlock()
+
+	if len(domains) == 0 {
+		return nil
+	}
+
+	patterns := newPatternSet(domains)
+	s.mu.Lock()
+	s.setDomainsLocked(domains, patterns)
+	s.activeSource = active
+	s.lastRefresh = newest
+	s.isReady = true
+	s.mu.Unlock()
+
+	s.logger.Info("disposable domains list restored from snapshot",
+		slog.String("path", s.snapshotPath),
+		slog.Int("domains_count", len(domains)),
+		slog.Time("saved_at", snap.SavedAt))
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never observe a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSnapshotWarmStart(t *testing.T) {
+	var broken atomic.Bool
+	var notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "tempmail.com\n")
+	}))
+	t.Cleanup(srv.Close)
+
+	opts := Options{
+		ListURLs:     []string{srv.URL + "/list.txt"},
+		SnapshotPath: filepath.Join(t.TempDir(), "snapshot.json"),
+	}
+	newTestService(t, opts)
+
+	// A restarted service serves the snapshot while the source is down
+	broken.Store(true)
+	s := NewDisposableEmailService(opts, slog.New(slog.DiscardHandler))
+	if err := s.loadSnapshot(); err != nil {
+		t.Fatal(err)
+	}
+	if !s.IsReady() {
+		t.Fatal("service not ready after loading the snapshot")
+	}
+	if _, err := s.Refresh(); err == nil {
+		t.Error("Refresh() with a failing source = nil error")
+	}
+	if v, err := s.Check("a@tempmail.com"); err != nil || !v.Disposable {
+		t.Errorf("Check() = %+v, %v; want disposable from the snapshot", v, err)
+	}
+
+	// The saved ETag makes the next request conditional
+	broken.Store(false)
+	if _, err := s.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("got %d conditional hits, want 1", notModified.Load())
+	}
+	if v, _ := s.Check("a@tempmail.com"); !v.Disposable {
+		t.Error("snapshot data dropped after a 304")
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"missing", "", ""},
+		{"malformed", `{"version": 1,`, "failed to decode snapshot"},
+		{"other version", `{"version": 99, "sources": {}}`, "unsupported snapshot version 99"},
+		{"unknown source", `{"version": 1, "sources": {"https://old.example/list.txt": {"domains": ["a.com"]}}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			if tt.data != "" {
+				if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			s := NewDisposableEmailService(Options{ListURLs: []string{"https://example.com/list.txt"}, SnapshotPath: path}, slog.New(slog.DiscardHandler))
+			err := s.loadSnapshot()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("loadSnapshot() error = %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadSnapshot() error = %v, want %q", err, tt.wantErr)
+			}
+			if s.IsReady() {
+				t.Error("service ready without a usable snapshot")
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// List modes control how multiple list URLs are combined
+const (
+	// ListModeFallback uses the first URL that succeeds (failover only)
+	ListModeFallback = "fallback"
+	// ListModeUnion fetches every URL and merges the results (coverage)
+	ListModeUnion = "union"
+)
+
+// sourceState tracks the last known state of a single list source
+type sourceState struct {
+	etag        string
+	domains     map[string]bool // last good copy
+	lastSuccess time.Time
+	lastFailure time.Time
+	lastError   error
+}
+
+// SourceStatus reports the state of a single list source
+type SourceStatus struct {
+	URL string
+	// List is the host list kind (HostListMX, HostListNS or
+	// HostListFreeMail) for host list sources, empty for domain list sources
+	List        string
+	ETag        string
+	DomainCount int
+	LastSuccess time.Time
+	LastFailure time.Time
+	LastError   string
+	// Active is set for the source serving data in fallback mode and for
+	// every source with data in union mode
+	Active bool
+}
+
+// Status reports the state of the loaded list and its sources
+type Status struct {
+	Ready       bool
+	Mode        string
+	DomainCount int
+	LastRefresh time.Time
+	// Degraded is set while no list has ever loaded (see FailurePolicy*)
+	Degraded bool
+	Sources  []SourceStatus
+	// HostSources are the sources of the MX and nameserver host lists and of
+	// the free-mail list
+	HostSources []SourceStatus
+}
+
+// Status returns a point-in-time report of the list and its sources
+func (s *DisposableEmailService) Status() Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := Status{
+		Ready:       s.isReady,
+		Mode:        s.listMode,
+		DomainCount: len(s.domains),
+		LastRefresh: s.lastRefresh,
+		Degraded:    !s.isReady,
+		Sources:     make([]SourceStatus, 0, len(s.listURLs)),
+	}
+	for _, url := range s.listURLs {
+		st := s.sources[url]
+		src := SourceStatus{
+			URL:         url,
+			ETag:        st.etag,
+			DomainCount: len(st.domains),
+			LastSuccess: st.lastSuccess,
+			LastFailure: st.lastFailure,
+		}
+		if st.lastError != nil {
+			src.LastError = st.lastError.Error()
+		}
+		if s.listMode == ListModeUnion {
+			src.Active = st.domains != nil
+		} else {
+			src.Active = url == s.activeSource
+		}
+		status.Sources = append(status.Sources, src)
+	}
+	for _, list := range s.hostLists() {
+		status.HostSources = append(status.HostSources, list.statusLocked()...)
+	}
+	return status
+}
+
+// fetchResult is the outcome of fetching a single source
+type fetchResult struct {
+	domains map[string]bool
+	etag    string
+	status  int
+	err     error
+}
+
+// recordSuccessLocked stores a freshly fetched copy for a source.
+// Caller must hold s.mu for writing.
+func (s *DisposableEmailService) recordSuccessLocked(url string, domains map[string]bool, etag string) {
+	st := s.sources[url]
+	st.domains = domains
+	st.lastSuccess = time.Now()
+	st.lastError = nil
+	if etag != "" {
+		st.etag = etag
+	}
+}
+
+// recordFailureLocked marks a failed fetch; the last good copy is kept.
+// Caller must hold s.mu for writing.
+func (s *DisposableEmailService) recordFailureLocked(url string, err error) {
+	st := s.sources[url]
+	st.lastFailure = time.Now()
+	st.lastError = err
+}
+
+// recordFailure is recordFailureLocked for callers not holding the lock
+func (s *DisposableEmailService) recordFailure(url string, err error) {
+	s.mu.Lock()
+	s.recordFailureLocked(url, err)
+	s.mu.Unlock()
+}
+
+// withOrigin maps every domain to the URL it was loaded from
+func withOrigin(domains map[string]bool, url string) map[string]string {
+	merged := make(map[string]string, len(domains))
+	for d := range domains {
+		merged[d] = url
+	}
+	return merged
+}
+
+// mergeSourcesLocked unions the last good copy of every source.
+// Earlier URLs win when a domain appears in several lists.
+// Caller must hold s.mu.
+func (s *DisposableEmailService) mergeSourcesLocked() map[string]string {
+	size := 0
+	for _, url := range s.listURLs {
+		size += len(s.sources[url].domains)
+	}
+
+	merged := make(map[string]string, size)
+	for _, url := range s.listURLs {
+		for d := range s.sources[url].domains {
+			if _, exists := merged[d]; !exists {
+				merged[d] = url
+			}
+		}
+	}
+	return merged
+}
+
+// refreshUnion fetches every URL concurrently and merges the results.
+// A failing source keeps contributing its last good copy.
+func (s *DisposableEmailService) refreshUnion(report *RefreshReport) error {
+	s.logger.Info("refreshing disposable domains list (union)",
+		slog.Int("urls", len(s.listURLs)))
+
+	results := make([]fetchResult, len(s.listURLs))
+	durations := make([]time.Duration, len(s.listURLs))
+	etags := make([]string, len(s.listURLs))
+	s.mu.RLock()
+	for i, url := range s.listURLs {
+		etags[i] = s.sources[url].etag
+	}
+	s.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	for i, url := range s.listURLs {
+		wg.Go(func() {
+			start := time.Now()
+			domains, etag, status, err := s.fetchSource(url, etags[i], normalizeListEntry)
+			results[i] = fetchResult{domains: domains, etag: etag, status: status, err: err}
+			durations[i] = time.Since(start)
+		})
+	}
+	wg.Wait()
+
+	for i, url := range s.listURLs {
+		report.addOutcome(url, results[i], durations[i])
+	}
+
+	var (
+		failed  int
+		lastErr error
+	)
+
+	s.mu.Lock()
+	for i, url := range s.listURLs {
+		res := results[i]
+		switch {
+		case res.err != nil:
+			failed++
+			lastErr = res.err
+			s.recordFailureLocked(url, res.err)
+		case res.status == http.StatusNotModified:
+			s.sources[url].lastSuccess = time.Now()
+		default:
+			s.recordSuccessLocked(url, res.domains, res.etag)
+		}
+	}
+
+	if failed == len(s.listURLs) {
+		s.mu.Unlock()
+		s.handleAllRefreshFailures(lastErr)
+		return fmt.Errorf("all %d URLs failed, last error: %w", len(s.listURLs), lastErr)
+	}
+
+	merged := s.mergeSourcesLocked()
+	s.mu.Unlock()
+	if len(merged) == 0 {
+		s.handleAllRefreshFailures(lastErr)
+		return fmt.Errorf("no source has data yet, last error: %w", lastErr)
+	}
+
+	// Refreshes are serialized, so the sources cannot change while the
+	// patterns compile without the lock
+	patterns := newPatternSet(merged)
+	s.mu.Lock()
+	s.setDomainsLocked(merged, patterns)
+	s.lastRefresh = time.Now()
+	s.isReady = true
+	s.mu.Unlock()
+
+	for i, url := range s.listURLs {
+		res := results[i]
+		switch {
+		case res.err != nil:
+			s.logger.Warn("failed to fetch from URL, keeping its previous contribution",
+				slog.String("url", url),
+				slog.Any("error", res.err))
+		case res.status == http.StatusNotModified:
+			s.logger.Info("disposable domains list not modified",
+				slog.String("source_url", url))
+		default:
+			s.logger.Info("disposable domains source refreshed",
+				slog.String("source_url", url),
+				slog.Int("domains_count", len(res.domains)))
+		}
+	}
+
+	s.logger.Info("disposable domains list merged successfully",
+		slog.Int("sources", len(s.listURLs)),
+		slog.Int("failed_sources", failed),
+		slog.Int("domains_count", len(merged)))
+
+	return nil
+}
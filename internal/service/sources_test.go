@@ -0,0 +1,110 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// listServer serves body until broken is set, then answers 500
+func listServer(t *testing.T, body string, broken *atomic.Bool) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if broken.Load() {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/list.txt"
+}
+
+func TestUnionMerge(t *testing.T) {
+	var ok atomic.Bool
+	first := listServer(t, "a.com\nshared.com\n", &ok)
+	second := listServer(t, "b.com\nshared.com\n", &ok)
+	s := newTestService(t, Options{ListURLs: []string{first, second}, ListMode: ListModeUnion})
+
+	tests := []struct {
+		domain     string
+		wantOrigin string
+	}{
+		{"a.com", first},
+		{"b.com", second},
+		// Earlier URLs win for domains listed by several sources
+		{"shared.com", first},
+		{"c.com", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			v, err := s.Lookup(tt.domain)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v.Disposable != (tt.wantOrigin != "") || v.Origin != tt.wantOrigin {
+				t.Errorf("Lookup(%q) = disposable %v, origin %q; want origin %q",
+					tt.domain, v.Disposable, v.Origin, tt.wantOrigin)
+			}
+		})
+	}
+	if got := s.DomainCount(); got != 3 {
+		t.Errorf("DomainCount() = %d, want 3", got)
+	}
+}
+
+func TestUnionKeepsFailedSourceContribution(t *testing.T) {
+	var firstBroken, secondBroken atomic.Bool
+	first := listServer(t, "a.com\n", &firstBroken)
+	second := listServer(t, "b.com\n", &secondBroken)
+	s := newTestService(t, Options{ListURLs: []string{first, second}, ListMode: ListModeUnion})
+
+	secondBroken.Store(true)
+	report, err := s.Refresh()
+	if err != nil {
+		t.Fatalf("Refresh() with one failing source: %v", err)
+	}
+	if !report.Success {
+		t.Error("report.Success = false, want true")
+	}
+
+	for _, d := range []string{"a.com", "b.com"} {
+		if v, _ := s.Lookup(d); !v.Disposable {
+			t.Errorf("Lookup(%q) not disposable after a failed refresh of its source", d)
+		}
+	}
+
+	status := s.Status()
+	if len(status.Sources) != 2 {
+		t.Fatalf("len(Sources) = %d, want 2", len(status.Sources))
+	}
+	if src := status.Sources[1]; !src.Active || src.LastError == "" || src.DomainCount != 1 {
+		t.Errorf("failed source status = %+v, want active with an error and its 1 domain", src)
+	}
+
+	firstBroken.Store(true)
+	if _, err := s.Refresh(); err == nil {
+		t.Error("Refresh() with every source failing = nil error")
+	}
+	if got := s.DomainCount(); got != 2 {
+		t.Errorf("DomainCount() after every source failed = %d, want 2", got)
+	}
+}
+
+func TestFallbackUsesFirstWorkingSource(t *testing.T) {
+	var firstBroken, secondBroken atomic.Bool
+	first := listServer(t, "a.com\n", &firstBroken)
+	second := listServer(t, "b.com\n", &secondBroken)
+
+	firstBroken.Store(true)
+	s := newTestService(t, Options{ListURLs: []string{first, second}})
+
+	if v, _ := s.Lookup("a.com"); v.Disposable {
+		t.Error("a.com disposable although its source failed")
+	}
+	if v, _ := s.Lookup("b.com"); !v.Disposable || v.Origin != second {
+		t.Errorf("Lookup(b.com) = disposable %v, origin %q; want origin %q", v.Disposable, v.Origin, second)
+	}
+}